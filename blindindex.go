@@ -0,0 +1,219 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//Define search index modes and defaults
+const (
+	SearchPrefix      = "prefix"
+	SearchNgram       = "ngram"
+	SearchFieldSuffix = "_search"
+	SearchTokenSize   = 8
+	searchMaxPrefix   = 16
+	searchIndexLabel  = "globe-protocol/encryption search index"
+)
+
+//Parsed search tag of a single struct field
+type searchIndex struct {
+	mode string
+	min  int
+	max  int
+}
+
+//Parse search tag, e.g. `search:"prefix,min=2,max=10"` or `search:"ngram=3"`
+func parseSearchTag(tag string) (*searchIndex, error) {
+	if tag == "" {
+		return nil, nil
+	}
+
+	idx := &searchIndex{}
+	for i, option := range strings.Split(tag, ",") {
+		key, value := option, ""
+		if pos := strings.Index(option, "="); pos != -1 {
+			key, value = option[:pos], option[pos+1:]
+		}
+
+		if i == 0 {
+			if key != SearchPrefix && key != SearchNgram {
+				return nil, fmt.Errorf("%s is not a supported search mode", key)
+			}
+			idx.mode = key
+
+			if key == SearchNgram {
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 1 {
+					return nil, fmt.Errorf("ngram search requires a positive size, got %q", value)
+				}
+				idx.min, idx.max = n, n
+			} else {
+				idx.min, idx.max = 1, searchMaxPrefix
+			}
+
+			continue
+		}
+
+		if idx.mode != SearchPrefix || (key != "min" && key != "max") {
+			return nil, fmt.Errorf("%s is not a supported option for %s search", key, idx.mode)
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("search option %s requires a positive number, got %q", key, value)
+		}
+
+		if key == "min" {
+			idx.min = n
+		} else {
+			idx.max = n
+		}
+	}
+
+	if idx.min > idx.max {
+		return nil, fmt.Errorf("search option min (%d) is bigger than max (%d)", idx.min, idx.max)
+	}
+
+	return idx, nil
+}
+
+//Get all substrings of the value that should be indexed
+func (s *searchIndex) terms(value string) []string {
+	runes := []rune(normalizeSearchTerm(value))
+	terms := []string{}
+
+	switch s.mode {
+	case SearchPrefix:
+		for l := s.min; l <= s.max && l <= len(runes); l++ {
+			terms = append(terms, string(runes[:l]))
+		}
+	case SearchNgram:
+		seen := map[string]bool{}
+		for i := 0; i+s.min <= len(runes); i++ {
+			term := string(runes[i : i+s.min])
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+
+	return terms
+}
+
+//Get the substrings of a search term that should be matched against the index
+func (s *searchIndex) queryTerms(term string) ([]string, error) {
+	runes := []rune(normalizeSearchTerm(term))
+
+	switch s.mode {
+	case SearchPrefix:
+		if len(runes) < s.min {
+			return nil, fmt.Errorf("search term must be at least %d characters long", s.min)
+		}
+		//Longer prefixes are not indexed, the longest stored prefix matches every value starting with the term
+		if len(runes) > s.max {
+			runes = runes[:s.max]
+		}
+		return []string{string(runes)}, nil
+	default:
+		if len(runes) < s.min {
+			return nil, fmt.Errorf("search term must be at least %d characters long", s.min)
+		}
+		return s.terms(string(runes)), nil
+	}
+}
+
+//Lowercase and trim values so that searching is case insensitive
+func normalizeSearchTerm(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+//Create truncated HMAC tokens for the given terms, bound to the field name
+func (e *encryptionService) indexTokens(label string, fieldName string, terms []string) ([]string, error) {
+	if e.remote != nil {
+		return e.remote.indexTokens(label, fieldName, terms)
+	}
+
+	indexKey, err := e.deriveKey(label)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]string, 0, len(terms))
+	for _, term := range terms {
+		mac := hmac.New(sha256.New, indexKey)
+		mac.Write([]byte(fieldName))
+		mac.Write([]byte{0})
+		mac.Write([]byte(term))
+		tokens = append(tokens, hex.EncodeToString(mac.Sum(nil)[:SearchTokenSize]))
+	}
+
+	return tokens, nil
+}
+
+//Add search tokens of field to the output object if the field has a search tag
+func (e *encryptionService) addSearchTokens(returnObj map[string]interface{}, fieldName string, field *fieldPlan, value reflect.Value) error {
+	if field.searchErr != nil {
+		return fmt.Errorf("invalid search tag on field %s: %s", field.field.Name, field.searchErr)
+	}
+	if field.search == nil {
+		return nil
+	}
+
+	tokens, err := e.indexTokens(searchIndexLabel, fieldName, field.search.terms(field.encode(value)))
+	if err != nil {
+		return err
+	}
+
+	returnObj[fieldName+SearchFieldSuffix] = tokens
+
+	return nil
+}
+
+//Find the struct field of a model by its bson, ename or json field name
+func (e *encryptionService) findModelField(model interface{}, fieldName string) (reflect.StructField, error) {
+	object := reflect.Indirect(reflect.ValueOf(model))
+	if object.Kind() != reflect.Struct {
+		return reflect.StructField{}, errors.New("model has to be a struct")
+	}
+
+	for i := 0; i < object.NumField(); i++ {
+		field := object.Type().Field(i)
+
+		name, err := e.findFieldTag(field.Tag, []string{"bson", "ename", "json"})
+		if err == nil && name == fieldName {
+			return field, nil
+		}
+	}
+
+	return reflect.StructField{}, fmt.Errorf("field %s could not be found in the model", fieldName)
+}
+
+//Get the tokens that have to be matched to find documents where the field matches the search term
+func (e *encryptionService) SearchTokens(model interface{}, fieldName string, term string) ([]string, error) {
+	field, err := e.findModelField(model, fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := parseSearchTag(field.Tag.Get("search"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid search tag on field %s: %s", field.Name, err)
+	}
+	if idx == nil {
+		return nil, fmt.Errorf("field %s does not have a search index", fieldName)
+	}
+
+	terms, err := idx.queryTerms(term)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.indexTokens(searchIndexLabel, fieldName, terms)
+}
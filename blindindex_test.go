@@ -0,0 +1,131 @@
+package encryption
+
+import (
+	"testing"
+)
+
+type searchable struct {
+	Id    string `bson:"_id" encrypted:"false"`
+	Name  string `bson:"name" search:"prefix,min=2,max=8"`
+	Phone string `bson:"phone" search:"ngram=4"`
+	Email string `bson:"email"`
+}
+
+func Test_encryptionService_SearchTokens(t *testing.T) {
+	type args struct {
+		fieldName string
+		term      string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "prefix match on name",
+			args: args{
+				fieldName: "name",
+				term:      "JoH",
+			},
+			wantErr: false,
+		},
+		{
+			name: "full value longer than maximum prefix",
+			args: args{
+				fieldName: "name",
+				term:      "Johnson-Smith",
+			},
+			wantErr: false,
+		},
+		{
+			name: "ngram match on last four digits",
+			args: args{
+				fieldName: "phone",
+				term:      "5678",
+			},
+			wantErr: false,
+		},
+		{
+			name: "prefix shorter than minimum",
+			args: args{
+				fieldName: "name",
+				term:      "j",
+			},
+			wantErr: true,
+		},
+		{
+			name: "field without search index",
+			args: args{
+				fieldName: "email",
+				term:      "john",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := encryptionService{
+				key: []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254},
+			}
+
+			encryptedData, err := e.EncryptToInterface(searchable{
+				Id:    "1",
+				Name:  "Johnson-Smith",
+				Phone: "+31612345678",
+				Email: "john@example.com",
+			})
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while encrypting object", err)
+			}
+
+			tokens, err := e.SearchTokens(searchable{}, tt.args.fieldName, tt.args.term)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.SearchTokens() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			stored, ok := encryptedData[tt.args.fieldName+SearchFieldSuffix].([]string)
+			if !ok {
+				t.Fatalf("expected search tokens to be stored for %s", tt.args.fieldName)
+			}
+
+			for _, token := range tokens {
+				found := false
+				for _, s := range stored {
+					if s == token {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("token %s not found in stored tokens %v", token, stored)
+				}
+			}
+		})
+	}
+}
+
+func Test_parseSearchTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		wantErr bool
+	}{
+		{name: "prefix", tag: "prefix", wantErr: false},
+		{name: "prefix with bounds", tag: "prefix,min=3,max=5", wantErr: false},
+		{name: "ngram", tag: "ngram=3", wantErr: false},
+		{name: "ngram without size", tag: "ngram", wantErr: true},
+		{name: "unknown mode", tag: "suffix", wantErr: true},
+		{name: "min bigger than max", tag: "prefix,min=5,max=3", wantErr: true},
+		{name: "option on ngram", tag: "ngram=3,min=2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseSearchTag(tt.tag); (err != nil) != tt.wantErr {
+				t.Errorf("parseSearchTag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
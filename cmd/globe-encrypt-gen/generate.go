@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//Header of generated files, files starting with it are ignored when the package is loaded so that stale output cannot break generation
+const generatedHeader = "// Code generated by globe-encrypt-gen. DO NOT EDIT."
+
+//How a field is converted to and from the string that is encrypted, matching Encode of the encryption package
+const (
+	codecString  = "string"
+	codecBool    = "bool"
+	codecInt     = "int"
+	codecUint    = "uint"
+	codecFloat   = "float"
+	codecTime    = "time"
+	codecStrings = "strings"
+)
+
+//Bit sizes passed to strconv, 0 is the size of int
+var intBits = map[types.BasicKind]int{
+	types.Int8: 8, types.Int16: 16, types.Int32: 32, types.Int64: 64,
+	types.Uint8: 8, types.Uint16: 16, types.Uint32: 32, types.Uint64: 64, types.Uintptr: 64,
+}
+
+type field struct {
+	name      string
+	typ       string
+	tag       string
+	encrypted bool
+	codec     string
+	bits      int
+	//Named types like type Status string need a conversion
+	named bool
+}
+
+type model struct {
+	name   string
+	fields []field
+}
+
+type generator struct {
+	pkg     *types.Package
+	imports map[string]string
+}
+
+//Load the package in dir and generate the mirror structs and functions of the given types
+func generate(dir string, typeNames []string) ([]byte, error) {
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg, imports: map[string]string{"github.com/globe-protocol/encryption": "encryption"}}
+
+	var models []model
+	var problems []string
+	for _, name := range typeNames {
+		m, err := g.model(strings.TrimSpace(name))
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		models = append(models, m)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("cannot generate code:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	var body bytes.Buffer
+	for _, m := range models {
+		g.writeModel(&body, m)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n\npackage %s\n\nimport (\n", generatedHeader, pkg.Name())
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	//Standard library imports come first, separated from the others by a blank line
+	sort.SliceStable(paths, func(i, j int) bool {
+		return isStdImport(paths[i]) && !isStdImport(paths[j])
+	})
+	for i, path := range paths {
+		if i > 0 && isStdImport(paths[i-1]) && !isStdImport(path) {
+			out.WriteString("\n")
+		}
+		if name := g.imports[path]; name != filepath.Base(path) {
+			fmt.Fprintf(&out, "\t%s %q\n", name, path)
+		} else {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code, the following error occured: %s", err)
+	}
+
+	return src, nil
+}
+
+func isStdImport(path string) bool {
+	return !strings.Contains(strings.Split(path, "/")[0], ".")
+}
+
+//Parse and type check the non test files of the package, type errors elsewhere in the package are ignored
+func loadPackage(dir string) (*types.Package, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(src, []byte(generatedHeader)) {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files found in %s", dir)
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, nil)
+
+	return pkg, nil
+}
+
+func (g *generator) model(name string) (model, error) {
+	obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return model{}, fmt.Errorf("type %s not found in package %s", name, g.pkg.Name())
+	}
+
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return model{}, fmt.Errorf("type %s is not a struct", name)
+	}
+
+	m := model{name: name}
+	var problems []string
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		f := field{
+			name:      v.Name(),
+			typ:       types.TypeString(v.Type(), g.qualifier),
+			tag:       st.Tag(i),
+			encrypted: reflect.StructTag(st.Tag(i)).Get("encrypted") != "false",
+			named:     !types.Identical(v.Type(), v.Type().Underlying()),
+		}
+
+		switch {
+		case v.Embedded():
+			problems = append(problems, fmt.Sprintf("%s.%s: embedded fields are not supported", name, v.Name()))
+			continue
+		case strings.Contains(reflect.StructTag(st.Tag(i)).Get("encrypted"), ","):
+			//Keys, modes and associated data of fields are only applied by the struct functions of the service
+			problems = append(problems, fmt.Sprintf("%s.%s: encrypted tag options are not supported, use EncryptToInterface", name, v.Name()))
+			continue
+		case hasIndexTag(reflect.StructTag(st.Tag(i))):
+			//Blind index tokens are only computed by the struct functions of the service
+			problems = append(problems, fmt.Sprintf("%s.%s: search and range tags are not supported, use EncryptToInterface", name, v.Name()))
+			continue
+		case !f.encrypted:
+		case isByteSlice(v.Type().Underlying()):
+			//EncryptToInterface encrypts fmt.Sprint of the bytes, which Decrypt cannot restore for most byte values
+			problems = append(problems, fmt.Sprintf("%s.%s: type %s cannot be encrypted like EncryptToInterface does, tag it encrypted:\"false\"", name, v.Name(), f.typ))
+			continue
+		case types.TypeString(v.Type(), nil) == "time.Time":
+			f.codec = codecTime
+			g.imports["time"] = "time"
+		default:
+			f.codec, f.bits = codecOf(v.Type().Underlying())
+			if f.codec == "" {
+				problems = append(problems, fmt.Sprintf("%s.%s: type %s cannot be encrypted", name, v.Name(), f.typ))
+				continue
+			}
+		}
+
+		if f.encrypted {
+			g.imports["fmt"] = "fmt"
+		}
+		switch f.codec {
+		case codecBool, codecInt, codecUint, codecFloat:
+			g.imports["strconv"] = "strconv"
+		case codecStrings:
+			g.imports["strings"] = "strings"
+		}
+
+		m.fields = append(m.fields, f)
+	}
+	if len(problems) > 0 {
+		return model{}, fmt.Errorf("%s", strings.Join(problems, "\n  "))
+	}
+
+	return m, nil
+}
+
+//Get codec and bit size for strconv of an underlying type, empty codec when it is not supported
+func codecOf(t types.Type) (string, int) {
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.String:
+			return codecString, 0
+		case types.Bool:
+			return codecBool, 0
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+			return codecInt, intBits[t.Kind()]
+		case types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64, types.Uintptr:
+			return codecUint, intBits[t.Kind()]
+		case types.Float32:
+			return codecFloat, 32
+		case types.Float64:
+			return codecFloat, 64
+		}
+	case *types.Slice:
+		if elem, ok := t.Elem().(*types.Basic); ok && elem.Kind() == types.String {
+			return codecStrings, 0
+		}
+	}
+
+	return "", 0
+}
+
+func isByteSlice(t types.Type) bool {
+	slice, ok := t.(*types.Slice)
+	if !ok {
+		return false
+	}
+	elem, ok := slice.Elem().(*types.Basic)
+
+	return ok && elem.Kind() == types.Byte
+}
+
+func hasIndexTag(tag reflect.StructTag) bool {
+	_, search := tag.Lookup("search")
+	_, rng := tag.Lookup("range")
+
+	return search || rng
+}
+
+//Qualify types of other packages with their package name and remember the import
+func (g *generator) qualifier(pkg *types.Package) string {
+	if pkg == g.pkg {
+		return ""
+	}
+
+	g.imports[pkg.Path()] = pkg.Name()
+
+	return pkg.Name()
+}
+
+func (g *generator) writeModel(w *bytes.Buffer, m model) {
+	mirror := "Encrypted" + m.name
+
+	fmt.Fprintf(w, "\n//Encrypted form of %s, fields that are not tagged encrypted:\"false\" hold ciphertexts\n", m.name)
+	fmt.Fprintf(w, "type %s struct {\n", mirror)
+	for _, f := range m.fields {
+		typ := f.typ
+		if f.encrypted {
+			typ = "[]byte"
+		}
+		if f.tag != "" {
+			fmt.Fprintf(w, "\t%s %s `%s`\n", f.name, typ, f.tag)
+		} else {
+			fmt.Fprintf(w, "\t%s %s\n", f.name, typ)
+		}
+	}
+	w.WriteString("}\n")
+
+	fmt.Fprintf(w, "\n//Encrypt %s to %s without reflection\n", m.name, mirror)
+	fmt.Fprintf(w, "func Encrypt%s(service encryption.EncryptionService, v %s) (%s, error) {\n", m.name, m.name, mirror)
+	fmt.Fprintf(w, "\tvar out %s\n", mirror)
+	for _, f := range m.fields {
+		if f.encrypted {
+			w.WriteString("\tvar err error\n")
+			break
+		}
+	}
+	w.WriteString("\n")
+	for _, f := range m.fields {
+		if !f.encrypted {
+			fmt.Fprintf(w, "\tout.%s = v.%s\n", f.name, f.name)
+			continue
+		}
+
+		call := fmt.Sprintf("service.EncryptStr(%s)", encodeExpr(f, "v."+f.name))
+		fmt.Fprintf(w, "\tif out.%s, err = %s; err != nil {\n", f.name, call)
+		fmt.Fprintf(w, "\t\treturn out, fmt.Errorf(\"failed to encrypt field %s, the following error occured: %%s\", err)\n\t}\n", f.name)
+	}
+	w.WriteString("\n\treturn out, nil\n}\n")
+
+	fmt.Fprintf(w, "\n//Decrypt %s back to %s without reflection, nil ciphertexts are left as zero values\n", mirror, m.name)
+	fmt.Fprintf(w, "func Decrypt%s(service encryption.EncryptionService, v %s) (%s, error) {\n", m.name, mirror, m.name)
+	fmt.Fprintf(w, "\tvar out %s\n\n", m.name)
+	for _, f := range m.fields {
+		if !f.encrypted {
+			fmt.Fprintf(w, "\tout.%s = v.%s\n", f.name, f.name)
+			continue
+		}
+
+		fmt.Fprintf(w, "\tif v.%s != nil {\n", f.name)
+		fmt.Fprintf(w, "\t\ts, err := service.DecryptStr(v.%s)\n", f.name)
+		fmt.Fprintf(w, "\t\tif err != nil {\n\t\t\treturn out, fmt.Errorf(\"failed to decrypt field %s, the following error occured: %%s\", err)\n\t\t}\n", f.name)
+		writeDecode(w, f)
+		w.WriteString("\t}\n")
+	}
+	w.WriteString("\n\treturn out, nil\n}\n")
+}
+
+//Get expression converting x to the string that is encrypted
+func encodeExpr(f field, x string) string {
+	//Conversions are only written where the type differs so that the output reads like handwritten code
+	convert := func(typ string, needed bool) string {
+		if needed {
+			return typ + "(" + x + ")"
+		}
+		return x
+	}
+
+	switch f.codec {
+	case codecBool:
+		return fmt.Sprintf("strconv.FormatBool(%s)", convert("bool", f.named))
+	case codecInt:
+		return fmt.Sprintf("strconv.FormatInt(%s, 10)", convert("int64", true))
+	case codecUint:
+		return fmt.Sprintf("strconv.FormatUint(%s, 10)", convert("uint64", true))
+	case codecFloat:
+		return fmt.Sprintf("strconv.FormatFloat(%s, 'g', -1, %d)", convert("float64", f.named || f.bits == 32), f.bits)
+	case codecTime:
+		return fmt.Sprintf("%s.Format(time.RFC3339Nano)", x)
+	case codecStrings:
+		return fmt.Sprintf("strings.Join(%s, \"°\")", convert("[]string", f.named))
+	default:
+		return convert("string", f.named)
+	}
+}
+
+//Write statements converting the decrypted s to the field
+func writeDecode(w *bytes.Buffer, f field) {
+	convert := func(x string) string {
+		if f.codec == codecInt || f.codec == codecUint || (f.codec == codecFloat && f.bits == 32) || (f.named && f.codec != codecTime) {
+			return f.typ + "(" + x + ")"
+		}
+		return x
+	}
+
+	var parse string
+	switch f.codec {
+	case codecString:
+		fmt.Fprintf(w, "\t\tout.%s = %s\n", f.name, convert("s"))
+		return
+	case codecStrings:
+		fmt.Fprintf(w, "\t\tout.%s = %s\n", f.name, convert("strings.Split(s, \"°\")"))
+		return
+	case codecBool:
+		parse = "strconv.ParseBool(s)"
+	case codecInt:
+		parse = fmt.Sprintf("strconv.ParseInt(s, 10, %d)", f.bits)
+	case codecUint:
+		parse = fmt.Sprintf("strconv.ParseUint(s, 10, %d)", f.bits)
+	case codecFloat:
+		parse = fmt.Sprintf("strconv.ParseFloat(s, %d)", f.bits)
+	case codecTime:
+		parse = "time.Parse(time.RFC3339Nano, s)"
+	}
+
+	fmt.Fprintf(w, "\t\tval, err := %s\n", parse)
+	fmt.Fprintf(w, "\t\tif err != nil {\n\t\t\treturn out, fmt.Errorf(\"failed to decode field %s, the following error occured: %%s\", err)\n\t\t}\n", f.name)
+	fmt.Fprintf(w, "\t\tout.%s = %s\n", f.name, convert("val"))
+}
@@ -0,0 +1,29 @@
+//Package example holds a model used to test the code generated by globe-encrypt-gen
+package example
+
+import "time"
+
+//go:generate go run github.com/globe-protocol/encryption/cmd/globe-encrypt-gen -type Params,Flags
+
+type Status string
+
+type Params struct {
+	Id           string    `bson:"_id" encrypted:"false"`
+	Number       int       `bson:"number"`
+	Small        int16     `bson:"small"`
+	Count        uint32    `bson:"count"`
+	Ratio        float32   `bson:"ratio"`
+	Balance      float64   `bson:"balance"`
+	Availability bool      `bson:"availabillity"`
+	Testvar      string    `bson:"testvar"`
+	Status       Status    `bson:"status"`
+	Tags         []string  `bson:"tags"`
+	Avatar       []byte    `bson:"avatar" encrypted:"false"`
+	Created      time.Time `bson:"created"`
+	Updated      time.Time `bson:"updated" encrypted:"false"`
+}
+
+type Flags struct {
+	Id     string `bson:"_id" encrypted:"false"`
+	Active bool   `bson:"active" encrypted:"false"`
+}
@@ -0,0 +1,94 @@
+package example
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/globe-protocol/encryption"
+)
+
+func testParams() Params {
+	return Params{
+		Id:           "1",
+		Number:       -100000,
+		Small:        -300,
+		Count:        70000,
+		Ratio:        0.1,
+		Balance:      1250.75,
+		Availability: true,
+		Testvar:      "secret",
+		Status:       "active",
+		Tags:         []string{"a", "b"},
+		Avatar:       []byte{0, 1, 2, 255},
+		Created:      time.Date(2024, 3, 1, 12, 30, 0, 5, time.UTC),
+		Updated:      time.Date(2024, 3, 2, 12, 30, 0, 0, time.UTC),
+	}
+}
+
+func Test_EncryptParams(t *testing.T) {
+	service := encryption.NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+	in := testParams()
+
+	encrypted, err := EncryptParams(service, in)
+	if err != nil {
+		t.Fatalf("EncryptParams() error = %v", err)
+	}
+	if encrypted.Id != in.Id || !encrypted.Updated.Equal(in.Updated) {
+		t.Errorf("EncryptParams() changed fields that are not encrypted: %+v", encrypted)
+	}
+
+	got, err := DecryptParams(service, encrypted)
+	if err != nil {
+		t.Fatalf("DecryptParams() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("DecryptParams() = %+v, want %+v", got, in)
+	}
+
+	//Values are encrypted the way EncryptToInterface encrypts them, so Decrypt can restore them as well
+	mirror := reflect.ValueOf(encrypted)
+	for i := 0; i < mirror.NumField(); i++ {
+		name := mirror.Type().Field(i).Name
+		if mirror.Type().Field(i).Tag.Get("encrypted") == "false" {
+			continue
+		}
+
+		plain, err := service.DecryptStr(mirror.Field(i).Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := encryption.Encode(reflect.ValueOf(in).FieldByName(name)); plain != want {
+			t.Errorf("EncryptParams() %s encrypted %q, want %q", name, plain, want)
+		}
+	}
+}
+
+func Test_DecryptParams(t *testing.T) {
+	service := encryption.NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+	other := encryption.NewEncryptionService([]byte("fedcba9876543210fedcba9876543210"))
+
+	encrypted, err := EncryptParams(service, testParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DecryptParams(other, encrypted); err == nil {
+		t.Errorf("DecryptParams() with other key should fail")
+	}
+
+	//Nil ciphertexts are zero values
+	got, err := DecryptParams(service, EncryptedParams{Id: "2"})
+	if err != nil {
+		t.Fatalf("DecryptParams() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, Params{Id: "2"}) {
+		t.Errorf("DecryptParams() = %+v, want zero values", got)
+	}
+
+	//Values that do not fit the field are rejected
+	encrypted.Small, _ = service.EncryptStr("40000")
+	if _, err := DecryptParams(service, encrypted); err == nil {
+		t.Errorf("DecryptParams() of out of range int16 should fail")
+	}
+}
@@ -0,0 +1,206 @@
+// Code generated by globe-encrypt-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globe-protocol/encryption"
+)
+
+// Encrypted form of Params, fields that are not tagged encrypted:"false" hold ciphertexts
+type EncryptedParams struct {
+	Id           string    `bson:"_id" encrypted:"false"`
+	Number       []byte    `bson:"number"`
+	Small        []byte    `bson:"small"`
+	Count        []byte    `bson:"count"`
+	Ratio        []byte    `bson:"ratio"`
+	Balance      []byte    `bson:"balance"`
+	Availability []byte    `bson:"availabillity"`
+	Testvar      []byte    `bson:"testvar"`
+	Status       []byte    `bson:"status"`
+	Tags         []byte    `bson:"tags"`
+	Avatar       []byte    `bson:"avatar" encrypted:"false"`
+	Created      []byte    `bson:"created"`
+	Updated      time.Time `bson:"updated" encrypted:"false"`
+}
+
+// Encrypt Params to EncryptedParams without reflection
+func EncryptParams(service encryption.EncryptionService, v Params) (EncryptedParams, error) {
+	var out EncryptedParams
+	var err error
+
+	out.Id = v.Id
+	if out.Number, err = service.EncryptStr(strconv.FormatInt(int64(v.Number), 10)); err != nil {
+		return out, fmt.Errorf("failed to encrypt field Number, the following error occured: %s", err)
+	}
+	if out.Small, err = service.EncryptStr(strconv.FormatInt(int64(v.Small), 10)); err != nil {
+		return out, fmt.Errorf("failed to encrypt field Small, the following error occured: %s", err)
+	}
+	if out.Count, err = service.EncryptStr(strconv.FormatUint(uint64(v.Count), 10)); err != nil {
+		return out, fmt.Errorf("failed to encrypt field Count, the following error occured: %s", err)
+	}
+	if out.Ratio, err = service.EncryptStr(strconv.FormatFloat(float64(v.Ratio), 'g', -1, 32)); err != nil {
+		return out, fmt.Errorf("failed to encrypt field Ratio, the following error occured: %s", err)
+	}
+	if out.Balance, err = service.EncryptStr(strconv.FormatFloat(v.Balance, 'g', -1, 64)); err != nil {
+		return out, fmt.Errorf("failed to encrypt field Balance, the following error occured: %s", err)
+	}
+	if out.Availability, err = service.EncryptStr(strconv.FormatBool(v.Availability)); err != nil {
+		return out, fmt.Errorf("failed to encrypt field Availability, the following error occured: %s", err)
+	}
+	if out.Testvar, err = service.EncryptStr(v.Testvar); err != nil {
+		return out, fmt.Errorf("failed to encrypt field Testvar, the following error occured: %s", err)
+	}
+	if out.Status, err = service.EncryptStr(string(v.Status)); err != nil {
+		return out, fmt.Errorf("failed to encrypt field Status, the following error occured: %s", err)
+	}
+	if out.Tags, err = service.EncryptStr(strings.Join(v.Tags, "°")); err != nil {
+		return out, fmt.Errorf("failed to encrypt field Tags, the following error occured: %s", err)
+	}
+	out.Avatar = v.Avatar
+	if out.Created, err = service.EncryptStr(v.Created.Format(time.RFC3339Nano)); err != nil {
+		return out, fmt.Errorf("failed to encrypt field Created, the following error occured: %s", err)
+	}
+	out.Updated = v.Updated
+
+	return out, nil
+}
+
+// Decrypt EncryptedParams back to Params without reflection, nil ciphertexts are left as zero values
+func DecryptParams(service encryption.EncryptionService, v EncryptedParams) (Params, error) {
+	var out Params
+
+	out.Id = v.Id
+	if v.Number != nil {
+		s, err := service.DecryptStr(v.Number)
+		if err != nil {
+			return out, fmt.Errorf("failed to decrypt field Number, the following error occured: %s", err)
+		}
+		val, err := strconv.ParseInt(s, 10, 0)
+		if err != nil {
+			return out, fmt.Errorf("failed to decode field Number, the following error occured: %s", err)
+		}
+		out.Number = int(val)
+	}
+	if v.Small != nil {
+		s, err := service.DecryptStr(v.Small)
+		if err != nil {
+			return out, fmt.Errorf("failed to decrypt field Small, the following error occured: %s", err)
+		}
+		val, err := strconv.ParseInt(s, 10, 16)
+		if err != nil {
+			return out, fmt.Errorf("failed to decode field Small, the following error occured: %s", err)
+		}
+		out.Small = int16(val)
+	}
+	if v.Count != nil {
+		s, err := service.DecryptStr(v.Count)
+		if err != nil {
+			return out, fmt.Errorf("failed to decrypt field Count, the following error occured: %s", err)
+		}
+		val, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return out, fmt.Errorf("failed to decode field Count, the following error occured: %s", err)
+		}
+		out.Count = uint32(val)
+	}
+	if v.Ratio != nil {
+		s, err := service.DecryptStr(v.Ratio)
+		if err != nil {
+			return out, fmt.Errorf("failed to decrypt field Ratio, the following error occured: %s", err)
+		}
+		val, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return out, fmt.Errorf("failed to decode field Ratio, the following error occured: %s", err)
+		}
+		out.Ratio = float32(val)
+	}
+	if v.Balance != nil {
+		s, err := service.DecryptStr(v.Balance)
+		if err != nil {
+			return out, fmt.Errorf("failed to decrypt field Balance, the following error occured: %s", err)
+		}
+		val, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return out, fmt.Errorf("failed to decode field Balance, the following error occured: %s", err)
+		}
+		out.Balance = val
+	}
+	if v.Availability != nil {
+		s, err := service.DecryptStr(v.Availability)
+		if err != nil {
+			return out, fmt.Errorf("failed to decrypt field Availability, the following error occured: %s", err)
+		}
+		val, err := strconv.ParseBool(s)
+		if err != nil {
+			return out, fmt.Errorf("failed to decode field Availability, the following error occured: %s", err)
+		}
+		out.Availability = val
+	}
+	if v.Testvar != nil {
+		s, err := service.DecryptStr(v.Testvar)
+		if err != nil {
+			return out, fmt.Errorf("failed to decrypt field Testvar, the following error occured: %s", err)
+		}
+		out.Testvar = s
+	}
+	if v.Status != nil {
+		s, err := service.DecryptStr(v.Status)
+		if err != nil {
+			return out, fmt.Errorf("failed to decrypt field Status, the following error occured: %s", err)
+		}
+		out.Status = Status(s)
+	}
+	if v.Tags != nil {
+		s, err := service.DecryptStr(v.Tags)
+		if err != nil {
+			return out, fmt.Errorf("failed to decrypt field Tags, the following error occured: %s", err)
+		}
+		out.Tags = strings.Split(s, "°")
+	}
+	out.Avatar = v.Avatar
+	if v.Created != nil {
+		s, err := service.DecryptStr(v.Created)
+		if err != nil {
+			return out, fmt.Errorf("failed to decrypt field Created, the following error occured: %s", err)
+		}
+		val, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return out, fmt.Errorf("failed to decode field Created, the following error occured: %s", err)
+		}
+		out.Created = val
+	}
+	out.Updated = v.Updated
+
+	return out, nil
+}
+
+// Encrypted form of Flags, fields that are not tagged encrypted:"false" hold ciphertexts
+type EncryptedFlags struct {
+	Id     string `bson:"_id" encrypted:"false"`
+	Active bool   `bson:"active" encrypted:"false"`
+}
+
+// Encrypt Flags to EncryptedFlags without reflection
+func EncryptFlags(service encryption.EncryptionService, v Flags) (EncryptedFlags, error) {
+	var out EncryptedFlags
+
+	out.Id = v.Id
+	out.Active = v.Active
+
+	return out, nil
+}
+
+// Decrypt EncryptedFlags back to Flags without reflection, nil ciphertexts are left as zero values
+func DecryptFlags(service encryption.EncryptionService, v EncryptedFlags) (Flags, error) {
+	var out Flags
+
+	out.Id = v.Id
+	out.Active = v.Active
+
+	return out, nil
+}
@@ -0,0 +1,58 @@
+//Command globe-encrypt-gen generates encrypted mirror structs and reflection-free functions that convert between a model and its mirror.
+//
+//It is meant to be used with go generate next to the model:
+//
+//	//go:generate go run github.com/globe-protocol/encryption/cmd/globe-encrypt-gen -type Params
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const usage = `usage: globe-encrypt-gen -type Name[,Name...] [-dir DIR] [-output FILE]
+
+For every type Name a struct EncryptedName and the functions EncryptName and DecryptName are
+generated. Fields are encrypted unless they are tagged encrypted:"false", like EncryptToInterface does.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "globe-encrypt-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("globe-encrypt-gen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+
+	typeNames := fs.String("type", "", "comma separated names of the model types")
+	dir := fs.String("dir", ".", "directory of the package holding the models")
+	output := fs.String("output", "", "output file, defaults to <type>_encrypted.go in the package directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *typeNames == "" {
+		return errors.New("-type is required\n\n" + usage)
+	}
+	names := strings.Split(*typeNames, ",")
+
+	if *output == "" {
+		*output = filepath.Join(*dir, strings.ToLower(names[0])+"_encrypted.go")
+	}
+
+	src, err := generate(*dir, names)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(*output, src, 0644)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//The checked in output of the example package must be what the generator produces now
+func Test_generate_Example(t *testing.T) {
+	want, err := os.ReadFile(filepath.Join("internal", "example", "params_encrypted.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := generate(filepath.Join("internal", "example"), []string{"Params", "Flags"})
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("generate() output differs from params_encrypted.go, run go generate ./cmd/globe-encrypt-gen/internal/example")
+	}
+}
+
+func Test_generate(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		types    []string
+		wantErr  []string
+		wantCode []string
+	}{
+		{
+			name:     "named types of other packages",
+			src:      "package models\n\nimport \"net/netip\"\n\ntype IP string\n\ntype Host struct {\n\tName string\n\tAddr IP\n\tPrefix netip.Prefix `encrypted:\"false\"`\n}\n",
+			types:    []string{"Host"},
+			wantCode: []string{"Prefix netip.Prefix `encrypted:\"false\"`", "out.Addr = IP(s)", "\"net/netip\""},
+		},
+		{
+			name:    "unknown type",
+			src:     "package models\n\ntype Host struct{}\n",
+			types:   []string{"Missing"},
+			wantErr: []string{"type Missing not found"},
+		},
+		{
+			name:    "not a struct",
+			src:     "package models\n\ntype Host string\n",
+			types:   []string{"Host"},
+			wantErr: []string{"type Host is not a struct"},
+		},
+		{
+			name:    "all problems are reported",
+			src:     "package models\n\ntype Base struct{}\n\ntype Host struct {\n\tBase\n\tPorts []int\n\tMeta map[string]string\n}\n",
+			types:   []string{"Host"},
+			wantErr: []string{"Host.Base: embedded fields are not supported", "Host.Ports: type []int cannot be encrypted", "Host.Meta: type map[string]string cannot be encrypted"},
+		},
+		{
+			name:    "encrypted tag options",
+			src:     "package models\n\ntype Host struct {\n\tName string `encrypted:\"true,mode=deterministic\"`\n}\n",
+			types:   []string{"Host"},
+			wantErr: []string{"Host.Name: encrypted tag options are not supported"},
+		},
+		{
+			name:    "encrypted bytes",
+			src:     "package models\n\ntype Raw []byte\n\ntype Host struct {\n\tKey []byte\n\tCert Raw\n}\n",
+			types:   []string{"Host"},
+			wantErr: []string{"Host.Key: type []byte cannot be encrypted like EncryptToInterface does", "Host.Cert: type Raw cannot be encrypted"},
+		},
+		{
+			name:    "index tags",
+			src:     "package models\n\ntype Host struct {\n\tName string `bson:\"name\" search:\"prefix\"`\n\tPort int `bson:\"port\" range:\"width=100\"`\n\tKey []byte `bson:\"key\" encrypted:\"false\"`\n}\n",
+			types:   []string{"Host"},
+			wantErr: []string{"Host.Name: search and range tags are not supported", "Host.Port: search and range tags are not supported"},
+		},
+		{
+			name:     "unsupported types that are not encrypted",
+			src:      "package models\n\ntype Host struct {\n\tPorts []int `encrypted:\"false\"`\n}\n",
+			types:    []string{"Host"},
+			wantCode: []string{"Ports []int `encrypted:\"false\"`"},
+		},
+		{
+			name:    "stale output is ignored",
+			src:     generatedHeader + "\n\npackage models\n\nvar broken = undefined\n",
+			types:   []string{"Host"},
+			wantErr: []string{"no Go files found"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := generate(dir, tt.types)
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Fatalf("generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("generate() error = %v, want it to contain %q", err, want)
+				}
+			}
+			for _, want := range tt.wantCode {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("generate() = %s, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func Test_run(t *testing.T) {
+	dir := t.TempDir()
+	src := "package models\n\ntype Host struct {\n\tName string `bson:\"name\"`\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"-dir", dir}, io.Discard); err == nil {
+		t.Errorf("run() without -type should fail")
+	}
+
+	if err := run([]string{"-dir", dir, "-type", "Host"}, io.Discard); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, "host_encrypted.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "func EncryptHost(service encryption.EncryptionService, v Host) (EncryptedHost, error)") {
+		t.Errorf("run() wrote %s", out)
+	}
+
+	//Running again must not be affected by the earlier output
+	if err := run([]string{"-dir", dir, "-type", "Host"}, io.Discard); err != nil {
+		t.Errorf("run() second time error = %v", err)
+	}
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/globe-protocol/encryption"
+)
+
+func keygen(args []string, stdout io.Writer) error {
+	if err := newFlagSet("keygen", nil).Parse(args); err != nil {
+		return err
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(key))
+
+	return err
+}
+
+func newKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func keyring(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected keyring new or keyring rotate")
+	}
+
+	fs := newFlagSet("keyring "+args[0], nil)
+	out := fs.String("out", "", "keyring file to create")
+	path := fs.String("keyring", "", "keyring file to rotate")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "new":
+		if *out == "" {
+			return errors.New("keyring new requires -out")
+		}
+
+		return encryption.WriteKeyringFile(*out, encryption.Keyring{
+			Current: encryption.DefaultKeyVersion,
+			Keys:    map[uint32][]byte{encryption.DefaultKeyVersion: key},
+			Derive:  encryption.DefaultKeyVersion,
+		}, false)
+	case "rotate":
+		if *path == "" {
+			return errors.New("keyring rotate requires -keyring")
+		}
+
+		keyring, err := encryption.ReadKeyringFile(*path)
+		if err != nil {
+			return err
+		}
+		if len(keyring.Keys) == 0 {
+			return fmt.Errorf("keyring %s contains no keys", *path)
+		}
+
+		//Pin the version keys are derived from so that blind indexes and streams keep working after the rotation
+		keyring.Derive = keyring.DeriveVersion()
+		for version := range keyring.Keys {
+			if version > keyring.Current {
+				keyring.Current = version
+			}
+		}
+		keyring.Current++
+		keyring.Keys[keyring.Current] = key
+
+		return encryption.WriteKeyringFile(*path, keyring, true)
+	default:
+		return fmt.Errorf("unknown keyring command %q", args[0])
+	}
+}
+
+func crypt(cmd string, args []string, stdin io.Reader, stdout io.Writer) error {
+	var keys keyFlags
+	fs := newFlagSet(cmd, &keys)
+	text := fs.Bool("text", false, "print a text ciphertext instead of base64")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	service, err := keys.service()
+	if err != nil {
+		return err
+	}
+
+	value, err := readValue(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	if cmd == "encrypt" {
+		out, err := encryptValue(service, value, *text)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(stdout, out)
+		return err
+	}
+
+	plain, err := decryptValue(service, value)
+	if err != nil {
+		return err
+	}
+
+	_, err = stdout.Write(plain)
+
+	return err
+}
+
+func encryptValue(service dataService, value []byte, text bool) (string, error) {
+	if text {
+		return service.EncryptStrText(string(value))
+	}
+
+	b, err := service.EncryptByt(value)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+//Decrypt text ciphertext or standard base64 of a binary ciphertext
+func decryptValue(service dataService, value []byte) ([]byte, error) {
+	value = bytes.TrimSpace(value)
+	if bytes.HasPrefix(value, []byte(encryption.TextPrefix)) {
+		return service.DecryptByt(value)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(string(value))
+	if err != nil {
+		return nil, errors.New("ciphertext should be a text ciphertext or standard base64")
+	}
+
+	return service.DecryptByt(b)
+}
+
+func cryptFile(cmd string, args []string) error {
+	var keys keyFlags
+	fs := newFlagSet(cmd, &keys)
+	in := fs.String("in", "", "source file")
+	out := fs.String("out", "", "destination file")
+	force := fs.Bool("force", false, "overwrite an existing destination file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *out == "" {
+		return fmt.Errorf("%s requires -in and -out", cmd)
+	}
+
+	service, err := keys.service()
+	if err != nil {
+		return err
+	}
+
+	var opts []encryption.FileOption
+	if *force {
+		opts = append(opts, encryption.WithOverwrite())
+	}
+
+	if cmd == "encrypt-file" {
+		return service.EncryptFile(*in, *out, opts...)
+	}
+
+	return service.DecryptFile(*in, *out, opts...)
+}
+
+func cryptStream(cmd string, args []string, stdin io.Reader, stdout io.Writer) error {
+	var keys keyFlags
+	fs := newFlagSet(cmd, &keys)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	service, err := keys.service()
+	if err != nil {
+		return err
+	}
+
+	if cmd == "encrypt-stream" {
+		w, err := service.NewEncryptWriter(stdout)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, stdin); err != nil {
+			return err
+		}
+
+		return w.Close()
+	}
+
+	r, err := service.NewDecryptReader(stdin)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(stdout, r)
+
+	return err
+}
+
+//Encrypt or decrypt top level fields of a JSON object, all fields are used when no fields are given
+func cryptJSON(cmd string, args []string, stdin io.Reader, stdout io.Writer) error {
+	var keys keyFlags
+	fs := newFlagSet(cmd, &keys)
+	fields := fs.String("fields", "", "comma separated fields, all fields when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	service, err := keys.service()
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(stdin)
+	dec.UseNumber()
+
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("input should be a JSON object, the following error occured: %s", err)
+	}
+
+	selected := map[string]bool{}
+	for _, f := range strings.Split(*fields, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			selected[f] = true
+		}
+	}
+
+	for name, value := range doc {
+		if len(selected) > 0 && !selected[name] {
+			continue
+		}
+		if value == nil {
+			continue
+		}
+
+		if cmd == "encrypt-json" {
+			plain, err := encodeJSONValue(value)
+			if err != nil {
+				return fmt.Errorf("failed to encode field %s, the following error occured: %s", name, err)
+			}
+
+			//Same as the []byte values of EncryptToJSON
+			b, err := service.EncryptStr(plain)
+			if err != nil {
+				return err
+			}
+			doc[name] = b
+			continue
+		}
+
+		ciphertext, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("field %s is not an encrypted value", name)
+		}
+
+		plain, err := decryptValue(service, []byte(ciphertext))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt field %s, the following error occured: %s", name, err)
+		}
+		doc[name] = decodeJSONValue(string(plain))
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(doc)
+}
+
+//Encode JSON value the way Encode encodes the matching Go value
+func encodeJSONValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				b, err := json.Marshal(v)
+				return string(b), err
+			}
+			strs = append(strs, s)
+		}
+
+		return strings.Join(strs, "°"), nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
+
+//Undo encodeJSONValue, strings joined with ° become arrays and numbers, booleans, arrays and objects are parsed back
+func decodeJSONValue(plain string) interface{} {
+	if strings.Contains(plain, "°") {
+		items := strings.Split(plain, "°")
+		values := make([]interface{}, len(items))
+		for i, item := range items {
+			values[i] = item
+		}
+
+		return values
+	}
+
+	//Surrounding whitespace would be lost, so such values were strings
+	if strings.TrimSpace(plain) != plain {
+		return plain
+	}
+
+	dec := json.NewDecoder(strings.NewReader(plain))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return plain
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return plain
+	}
+
+	switch value.(type) {
+	case json.Number, bool, []interface{}, map[string]interface{}:
+		return value
+	default:
+		return plain
+	}
+}
+
+//Show what can be read from a ciphertext without decrypting it, with a key or keyring every key is tried
+func inspect(args []string, stdin io.Reader, stdout io.Writer) error {
+	var keys keyFlags
+	fs := newFlagSet("inspect", &keys)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	value, err := readValue(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	candidates, err := keys.keys()
+	if err != nil {
+		return err
+	}
+
+	var info *encryption.CiphertextInfo
+	var diagnosis *encryption.Diagnosis
+	if candidates != nil {
+		diagnosis = encryption.Diagnose(value, candidates)
+		info = &diagnosis.CiphertextInfo
+	} else {
+		info = encryption.Inspect(value)
+	}
+
+	fmt.Fprintf(stdout, "format: %s\n", info.Format)
+	fmt.Fprintf(stdout, "encoding: %s\n", info.Encoding)
+	if info.Version != 0 {
+		fmt.Fprintf(stdout, "version: %d\n", info.Version)
+	}
+	if info.Algorithm != "" {
+		fmt.Fprintf(stdout, "algorithm: %s\n", info.Algorithm)
+	}
+	if info.KeyID != "" {
+		fmt.Fprintf(stdout, "key id: %s\n", info.KeyID)
+	}
+	if info.Nonce != nil {
+		fmt.Fprintf(stdout, "nonce: %x\n", info.Nonce)
+	}
+	if info.Salt != nil {
+		fmt.Fprintf(stdout, "salt: %x\n", info.Salt)
+	}
+	if info.Format != encryption.FormatUnknown {
+		fmt.Fprintf(stdout, "payload length: %d\n", info.PayloadLength)
+	}
+	for _, r := range info.Recipients {
+		fmt.Fprintf(stdout, "recipient: %s %s\n", r.Type, r.KeyID)
+	}
+	if !info.IssuedAt.IsZero() {
+		fmt.Fprintf(stdout, "issued at: %s\n", info.IssuedAt.UTC().Format(time.RFC3339))
+	}
+	if !info.ExpiresAt.IsZero() {
+		fmt.Fprintf(stdout, "expires at: %s\n", info.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	if diagnosis != nil && diagnosis.MatchedKey != "" {
+		fmt.Fprintf(stdout, "authenticated by: %s\n", diagnosis.MatchedKey)
+	}
+	for _, problem := range info.Problems {
+		fmt.Fprintf(stdout, "problem: %s\n", problem)
+	}
+
+	return nil
+}
+
+func rotate(args []string, stdin io.Reader, stdout io.Writer) error {
+	var keys keyFlags
+	fs := newFlagSet("rotate", &keys)
+	toKeyFile := fs.String("to-key-file", "", "file holding the base64 key to encrypt with")
+	toKeyring := fs.String("to-keyring", "", "keyring JSON file to encrypt with")
+	text := fs.Bool("text", false, "print text ciphertexts instead of base64")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	from, err := keys.service()
+	if err != nil {
+		return err
+	}
+
+	//Text ciphertexts can be rotated within a single keyring, old versions are decrypted and the current version encrypts
+	to := from
+	if *toKeyFile != "" || *toKeyring != "" {
+		if to, err = loadService(*toKeyFile, *toKeyring, false); err != nil {
+			return err
+		}
+	}
+
+	rotateOne := func(value string) error {
+		plain, err := decryptValue(from, []byte(value))
+		if err != nil {
+			return err
+		}
+
+		out, err := encryptValue(to, plain, *text || strings.HasPrefix(value, encryption.TextPrefix))
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(stdout, out)
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		for _, value := range fs.Args() {
+			if err := rotateOne(value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		if err := rotateOne(scanner.Text()); err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+	}
+
+	return scanner.Err()
+}
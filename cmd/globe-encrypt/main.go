@@ -0,0 +1,207 @@
+//Command globe-encrypt encrypts and decrypts strings, files, streams and JSON documents and manages keys
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/globe-protocol/encryption"
+)
+
+//Environment variable holding a base64 key, used when no key flag is given
+const keyEnv = "GLOBE_ENCRYPTION_KEY"
+
+const usage = `usage: globe-encrypt <command> [flags] [arguments]
+
+Keys:
+  keygen                      print a new random base64 key
+  keyring new -out FILE       create a keyring with a single key version
+  keyring rotate -keyring FILE
+                              add a new key version and make it current
+
+Data:
+  encrypt [-text] [VALUE]     encrypt VALUE or stdin like EncryptByt, printed as base64 or as text ciphertext
+  decrypt [VALUE]             decrypt base64 or text ciphertext from VALUE or stdin
+  encrypt-file -in SRC -out DST [-force]
+  decrypt-file -in SRC -out DST [-force]
+  encrypt-stream              encrypt stdin to stdout in the streaming format
+  decrypt-stream              decrypt stdin to stdout
+  encrypt-json [-fields a,b]  encrypt fields of the JSON object on stdin like EncryptToJSON
+  decrypt-json [-fields a,b]  decrypt fields of the JSON object on stdin, numbers, booleans, arrays and objects
+                              are parsed back and strings joined with ° become arrays, other values stay strings
+  inspect [VALUE]             show the format of a ciphertext, with a key or keyring also which key authenticates it
+  rotate -to-key-file FILE | -to-keyring FILE [-text]
+                              re-encrypt ciphertexts given as arguments or as lines on stdin
+
+Data commands read the key from -key-file or -keyring, or from the ` + keyEnv + ` environment variable.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "globe-encrypt:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "keygen":
+		return keygen(args, stdout)
+	case "keyring":
+		return keyring(args)
+	case "encrypt", "decrypt":
+		return crypt(cmd, args, stdin, stdout)
+	case "encrypt-file", "decrypt-file":
+		return cryptFile(cmd, args)
+	case "encrypt-stream", "decrypt-stream":
+		return cryptStream(cmd, args, stdin, stdout)
+	case "encrypt-json", "decrypt-json":
+		return cryptJSON(cmd, args, stdin, stdout)
+	case "inspect":
+		return inspect(args, stdin, stdout)
+	case "rotate":
+		return rotate(args, stdin, stdout)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", cmd, usage)
+	}
+}
+
+//Flags shared by all commands that need a key
+type keyFlags struct {
+	keyFile string
+	keyring string
+}
+
+func newFlagSet(name string, keys *keyFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if keys != nil {
+		fs.StringVar(&keys.keyFile, "key-file", "", "file holding a base64 key")
+		fs.StringVar(&keys.keyring, "keyring", "", "keyring JSON file")
+	}
+
+	return fs
+}
+
+//Capabilities of the services of the encryption package that the data commands use
+type dataService interface {
+	encryption.EncryptionService
+	encryption.TextEncrypter
+	encryption.StreamEncrypter
+	encryption.FileEncrypter
+}
+
+func (k keyFlags) service() (dataService, error) {
+	return loadService(k.keyFile, k.keyring, true)
+}
+
+//Get the keys given by flags by name, keyring versions are named v1, v2 and so on, nil when no key flag is given
+func (k keyFlags) keys() (map[string][]byte, error) {
+	switch {
+	case k.keyring != "":
+		keyring, err := encryption.ReadKeyringFile(k.keyring)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := make(map[string][]byte, len(keyring.Keys))
+		for version, key := range keyring.Keys {
+			keys[fmt.Sprintf("v%d", version)] = key
+		}
+
+		return keys, nil
+	case k.keyFile != "":
+		b, err := os.ReadFile(k.keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+		if err != nil {
+			return nil, errors.New("key is not valid base64")
+		}
+
+		return map[string][]byte{k.keyFile: key}, nil
+	default:
+		return nil, nil
+	}
+}
+
+//Create service from a key file, a keyring file or the key environment variable
+func loadService(keyFile string, keyringFile string, useEnv bool) (dataService, error) {
+	service, err := newService(keyFile, keyringFile, useEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := service.(dataService)
+	if !ok {
+		return nil, errors.New("service does not support the data commands")
+	}
+
+	return data, nil
+}
+
+func newService(keyFile string, keyringFile string, useEnv bool) (encryption.EncryptionService, error) {
+	switch {
+	case keyFile != "" && keyringFile != "":
+		return nil, errors.New("use either a key file or a keyring")
+	case keyringFile != "":
+		keyring, err := encryption.ReadKeyringFile(keyringFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return encryption.NewKeyringEncryptionService(keyring)
+	case keyFile != "":
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return serviceFromBase64(string(b))
+	case useEnv && os.Getenv(keyEnv) != "":
+		return serviceFromBase64(os.Getenv(keyEnv))
+	default:
+		return nil, fmt.Errorf("no key given, use -key-file, -keyring or %s", keyEnv)
+	}
+}
+
+func serviceFromBase64(s string) (encryption.EncryptionService, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, errors.New("key is not valid base64")
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, errors.New("key should be 16, 24 or 32 bytes")
+	}
+
+	return encryption.NewEncryptionService(key), nil
+}
+
+//Get value from the only argument or from stdin
+func readValue(args []string, stdin io.Reader) ([]byte, error) {
+	switch len(args) {
+	case 0:
+		return io.ReadAll(stdin)
+	case 1:
+		return []byte(args[0]), nil
+	default:
+		return nil, errors.New("expected at most one value")
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/globe-protocol/encryption"
+)
+
+func runCmd(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+
+	var stdout bytes.Buffer
+	if err := run(args, strings.NewReader(stdin), &stdout); err != nil {
+		t.Fatalf("run(%v) error = %v", args, err)
+	}
+
+	return stdout.String()
+}
+
+func Test_run(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	keyringFile := filepath.Join(dir, "keyring.json")
+
+	if err := os.WriteFile(keyFile, []byte(runCmd(t, "", "keygen")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	runCmd(t, "", "keyring", "new", "-out", keyringFile)
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "string",
+			run: func(t *testing.T) {
+				ciphertext := runCmd(t, "", "encrypt", "-key-file", keyFile, "secret value")
+				if got := runCmd(t, ciphertext, "decrypt", "-key-file", keyFile); got != "secret value" {
+					t.Errorf("decrypt = %q, want %q", got, "secret value")
+				}
+			},
+		},
+		{
+			name: "text after keyring rotation",
+			run: func(t *testing.T) {
+				ciphertext := strings.TrimSpace(runCmd(t, "", "encrypt", "-text", "-keyring", keyringFile, "secret value"))
+				if !strings.HasPrefix(ciphertext, "globe:v1:") {
+					t.Fatalf("encrypt -text = %q", ciphertext)
+				}
+
+				runCmd(t, "", "keyring", "rotate", "-keyring", keyringFile)
+
+				rotated := strings.TrimSpace(runCmd(t, ciphertext+"\n", "rotate", "-keyring", keyringFile))
+				if !strings.HasPrefix(rotated, "globe:v2:") {
+					t.Errorf("rotate = %q, want version 2", rotated)
+				}
+				if got := runCmd(t, "", "decrypt", "-keyring", keyringFile, rotated); got != "secret value" {
+					t.Errorf("decrypt = %q, want %q", got, "secret value")
+				}
+			},
+		},
+		{
+			name: "rotate to other key",
+			run: func(t *testing.T) {
+				ciphertext := strings.TrimSpace(runCmd(t, "", "encrypt", "-key-file", keyFile, "secret value"))
+				rotated := strings.TrimSpace(runCmd(t, "", "rotate", "-key-file", keyFile, "-to-keyring", keyringFile, ciphertext))
+				if got := runCmd(t, "", "decrypt", "-keyring", keyringFile, rotated); got != "secret value" {
+					t.Errorf("decrypt = %q, want %q", got, "secret value")
+				}
+			},
+		},
+		{
+			name: "file",
+			run: func(t *testing.T) {
+				src := filepath.Join(dir, "plain.txt")
+				if err := os.WriteFile(src, []byte("file content"), 0600); err != nil {
+					t.Fatal(err)
+				}
+
+				runCmd(t, "", "encrypt-file", "-key-file", keyFile, "-in", src, "-out", src+".enc")
+				runCmd(t, "", "decrypt-file", "-key-file", keyFile, "-in", src+".enc", "-out", src+".dec")
+
+				got, err := os.ReadFile(src + ".dec")
+				if err != nil || string(got) != "file content" {
+					t.Errorf("decrypt-file wrote %q, %v", got, err)
+				}
+			},
+		},
+		{
+			name: "stream",
+			run: func(t *testing.T) {
+				encrypted := runCmd(t, "stream content", "encrypt-stream", "-key-file", keyFile)
+				if got := runCmd(t, encrypted, "decrypt-stream", "-key-file", keyFile); got != "stream content" {
+					t.Errorf("decrypt-stream = %q", got)
+				}
+				if got := runCmd(t, encrypted, "inspect"); !strings.Contains(got, "format: stream") {
+					t.Errorf("inspect = %q", got)
+				}
+				if got := runCmd(t, encrypted, "inspect", "-key-file", keyFile); !strings.Contains(got, "authenticated by: "+keyFile) {
+					t.Errorf("inspect -key-file = %q", got)
+				}
+				if got := runCmd(t, encrypted, "inspect", "-keyring", keyringFile); !strings.Contains(got, "problem: none of the keys") {
+					t.Errorf("inspect -keyring = %q", got)
+				}
+			},
+		},
+		{
+			name: "json",
+			run: func(t *testing.T) {
+				input := `{"active":true,"address":{"city":"Paris"},"age":42,"id":"1","ids":[1,2],"name":"John","score":1.5,"tags":["a","b"]}`
+				fields := "active,address,age,ids,name,score,tags"
+				encrypted := runCmd(t, input, "encrypt-json", "-key-file", keyFile, "-fields", fields)
+
+				var doc map[string]interface{}
+				if err := json.Unmarshal([]byte(encrypted), &doc); err != nil {
+					t.Fatal(err)
+				}
+				if doc["id"] != "1" || doc["name"] == "John" {
+					t.Errorf("encrypt-json = %s", encrypted)
+				}
+
+				decrypted := runCmd(t, encrypted, "decrypt-json", "-key-file", keyFile, "-fields", fields)
+				var got, want bytes.Buffer
+				if err := json.Compact(&got, []byte(decrypted)); err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Compact(&want, []byte(input)); err != nil {
+					t.Fatal(err)
+				}
+				if got.String() != want.String() {
+					t.Errorf("decrypt-json = %s, want %s", got.String(), want.String())
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, tt.run)
+	}
+
+	var stdout bytes.Buffer
+	if err := run([]string{"decrypt", "-key-file", keyFile, "bm90IGEgY2lwaGVydGV4dA=="}, strings.NewReader(""), &stdout); err == nil {
+		t.Errorf("expected error while decrypting invalid ciphertext")
+	}
+}
+
+func Test_keyring(t *testing.T) {
+	dir := t.TempDir()
+	keyringFile := filepath.Join(dir, "keyring.json")
+
+	runCmd(t, "", "keyring", "new", "-out", keyringFile)
+	if err := run([]string{"keyring", "new", "-out", keyringFile}, strings.NewReader(""), &bytes.Buffer{}); err == nil {
+		t.Errorf("keyring new should not overwrite an existing keyring")
+	}
+
+	runCmd(t, "", "keyring", "rotate", "-keyring", keyringFile)
+	keyring, err := encryption.ReadKeyringFile(keyringFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyring.Current != 2 || len(keyring.Keys) != 2 || keyring.Derive != 1 {
+		t.Errorf("rotated keyring has current %d, %d keys and derive version %d", keyring.Current, len(keyring.Keys), keyring.Derive)
+	}
+
+	//Keyrings without keys are rejected instead of panicking
+	emptyFile := filepath.Join(dir, "empty.json")
+	if err := os.WriteFile(emptyFile, []byte(`{"current":1}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"keyring", "rotate", "-keyring", emptyFile}, strings.NewReader(""), &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "contains no keys") {
+		t.Errorf("keyring rotate of empty keyring error = %v", err)
+	}
+
+	//Temp files are removed and no fixed temp path is used
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("directory contains %d files after writing keyrings, want 2", len(entries))
+	}
+}
+
+func Test_decodeJSONValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		plain string
+		want  interface{}
+	}{
+		{name: "string", plain: "John", want: "John"},
+		{name: "number", plain: "42", want: json.Number("42")},
+		{name: "float", plain: "-1.5e3", want: json.Number("-1.5e3")},
+		{name: "bool", plain: "false", want: false},
+		{name: "list", plain: "a°b°", want: []interface{}{"a", "b", ""}},
+		{name: "array", plain: `[1,"a"]`, want: []interface{}{json.Number("1"), "a"}},
+		{name: "object", plain: `{"a":1}`, want: map[string]interface{}{"a": json.Number("1")}},
+		{name: "null", plain: "null", want: "null"},
+		{name: "quoted", plain: `"a"`, want: `"a"`},
+		{name: "spaces", plain: " 42 ", want: " 42 "},
+		{name: "trailing", plain: "42 abc", want: "42 abc"},
+		{name: "empty", plain: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeJSONValue(tt.plain); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeJSONValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
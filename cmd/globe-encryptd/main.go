@@ -0,0 +1,97 @@
+//Command globe-encryptd serves the encryption service over HTTP for services that cannot use the Go package directly
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/globe-protocol/encryption"
+)
+
+//Config file, keyring files are resolved relative to the config file
+type config struct {
+	Keys map[string]keyConfig `json:"keys"`
+}
+
+type keyConfig struct {
+	KeyringFile string   `json:"keyring_file"`
+	Tokens      []string `json:"tokens"`
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8420", "address to listen on")
+	configPath := flag.String("config", "globe-encryptd.json", "path of the config file")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file, TLS is used when set")
+	tlsKey := flag.String("tls-key", "", "TLS private key file")
+	flag.Parse()
+
+	keys, err := loadKeys(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	handler, err := encryption.NewServer(keys)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf("serving %d keys on %s", len(keys), *addr)
+	if *tlsCert != "" {
+		err = srv.ListenAndServeTLS(*tlsCert, *tlsKey)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	log.Fatal(err)
+}
+
+func loadKeys(configPath string) (map[string]*encryption.ServerKey, error) {
+	b, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config, the following error occured: %s", err)
+	}
+
+	var cfg config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config, the following error occured: %s", err)
+	}
+
+	keys := make(map[string]*encryption.ServerKey, len(cfg.Keys))
+	for name, k := range cfg.Keys {
+		path := k.KeyringFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(configPath), path)
+		}
+
+		keyring, err := encryption.ReadKeyringFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load keyring of key %s, the following error occured: %s", name, err)
+		}
+
+		keys[name] = &encryption.ServerKey{
+			Keyring:  keyring,
+			Tokens:   k.Tokens,
+			OnRotate: saveKeyring(path),
+		}
+	}
+
+	return keys, nil
+}
+
+//Persist rotated keyring through a temp file that is synced and moved into place, so that a crash never leaves a partial keyring
+func saveKeyring(path string) func(encryption.Keyring) error {
+	return func(keyring encryption.Keyring) error {
+		return encryption.WriteKeyringFile(path, keyring, true)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/globe-protocol/encryption"
+)
+
+func Test_loadKeys(t *testing.T) {
+	dir := t.TempDir()
+	keyring := encryption.Keyring{Current: 1, Keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}, Derive: 1}
+	if err := encryption.WriteKeyringFile(filepath.Join(dir, "payments.json"), keyring, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{name: "relative keyring file", config: `{"keys":{"payments":{"keyring_file":"payments.json","tokens":["t"]}}}`},
+		{name: "absolute keyring file", config: `{"keys":{"payments":{"keyring_file":"` + filepath.Join(dir, "payments.json") + `","tokens":["t"]}}}`},
+		{name: "missing keyring file", config: `{"keys":{"payments":{"keyring_file":"missing.json"}}}`, wantErr: "failed to load keyring of key payments"},
+		{name: "invalid keyring file", config: `{"keys":{"payments":{"keyring_file":"broken.json"}}}`, wantErr: "failed to parse keyring"},
+		{name: "invalid config", config: `{"keys":`, wantErr: "failed to parse config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(dir, "globe-encryptd.json")
+			if err := os.WriteFile(configPath, []byte(tt.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			keys, err := loadKeys(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadKeys() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadKeys() error = %v", err)
+			}
+
+			key := keys["payments"]
+			if key == nil || key.Keyring.Current != 1 || !bytes.Equal(key.Keyring.Keys[1], keyring.Keys[1]) || key.Tokens[0] != "t" || key.OnRotate == nil {
+				t.Errorf("loadKeys() = %+v", key)
+			}
+		})
+	}
+
+	if _, err := loadKeys(filepath.Join(dir, "missing-config.json")); err == nil {
+		t.Errorf("loadKeys() of missing config should fail")
+	}
+}
+
+func Test_saveKeyring(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "payments.json")
+	if err := encryption.WriteKeyringFile(path, encryption.Keyring{Current: 1, Keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	//Concurrent rotations each write a complete keyring and never share a temp file
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = saveKeyring(path)(encryption.Keyring{
+				Current: 2,
+				Keys:    map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32), 2: bytes.Repeat([]byte{byte(i)}, 32)},
+				Derive:  1,
+			})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("saveKeyring() error = %v", err)
+		}
+	}
+
+	keyring, err := encryption.ReadKeyringFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyring.Current != 2 || len(keyring.Keys) != 2 || keyring.Derive != 1 {
+		t.Errorf("saved keyring has current %d, %d keys and derive version %d", keyring.Current, len(keyring.Keys), keyring.Derive)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory contains %d files after saving, temp files were left behind", len(entries))
+	}
+
+	if err := saveKeyring(filepath.Join(dir, "missing", "payments.json"))(keyring); err == nil {
+		t.Errorf("saveKeyring() into missing directory should fail")
+	}
+}
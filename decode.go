@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 //Define all types as enum
@@ -30,7 +31,12 @@ const (
 
 //Decode string value to desired type
 func Decode(s string, t reflect.Value) (interface{}, error) {
-	switch t.Type().String() {
+	return decodeType(t.Type().String(), s)
+}
+
+//Decode string value to the type with the given name
+func decodeType(typeName string, s string) (interface{}, error) {
+	switch typeName {
 	case Int8:
 		var i int8
 
@@ -76,7 +82,7 @@ func Decode(s string, t reflect.Value) (interface{}, error) {
 	case Int16:
 		var i int16
 
-		v, err := strconv.ParseInt(s, 10, 8)
+		v, err := strconv.ParseInt(s, 10, 16)
 		if err != nil {
 			return nil, err
 		}
@@ -88,7 +94,7 @@ func Decode(s string, t reflect.Value) (interface{}, error) {
 	case Uint16:
 		var u uint16
 
-		v, err := strconv.ParseUint(s, 10, 8)
+		v, err := strconv.ParseUint(s, 10, 16)
 		if err != nil {
 			return nil, err
 		}
@@ -100,7 +106,7 @@ func Decode(s string, t reflect.Value) (interface{}, error) {
 	case Int32:
 		var i int32
 
-		v, err := strconv.ParseInt(s, 10, 8)
+		v, err := strconv.ParseInt(s, 10, 32)
 		if err != nil {
 			return nil, err
 		}
@@ -112,7 +118,7 @@ func Decode(s string, t reflect.Value) (interface{}, error) {
 	case Uint32:
 		var u uint32
 
-		v, err := strconv.ParseUint(s, 10, 8)
+		v, err := strconv.ParseUint(s, 10, 32)
 		if err != nil {
 			return nil, err
 		}
@@ -124,7 +130,7 @@ func Decode(s string, t reflect.Value) (interface{}, error) {
 	case Int64:
 		var i int64
 
-		v, err := strconv.ParseInt(s, 10, 8)
+		v, err := strconv.ParseInt(s, 10, 64)
 		if err != nil {
 			return nil, err
 		}
@@ -136,7 +142,7 @@ func Decode(s string, t reflect.Value) (interface{}, error) {
 	case Uint64:
 		var u uint64
 
-		v, err := strconv.ParseUint(s, 10, 8)
+		v, err := strconv.ParseUint(s, 10, 64)
 		if err != nil {
 			return nil, err
 		}
@@ -209,6 +215,14 @@ func Decode(s string, t reflect.Value) (interface{}, error) {
 	case StringArr:
 		return strings.Split(s, "°"), nil
 
+	case Time:
+		v, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+
+		return v, nil
+
 	case Bool:
 		var b bool
 
@@ -220,6 +234,48 @@ func Decode(s string, t reflect.Value) (interface{}, error) {
 		return b, nil
 
 	default:
-		return nil, fmt.Errorf("%s is not a supported file type", t.Type().String())
+		return nil, fmt.Errorf("%s is not a supported file type", typeName)
+	}
+}
+
+//Decode string created by encodeValue to a value of the given type
+func decodeValue(s string, typ reflect.Type) (interface{}, error) {
+	out := reflect.New(typ).Elem()
+
+	var err error
+	switch {
+	case typ == reflect.TypeOf(time.Time{}):
+		var t time.Time
+		t, err = time.Parse(time.RFC3339Nano, s)
+		out.Set(reflect.ValueOf(t))
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8:
+		out.SetBytes([]byte(s))
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.String:
+		out.Set(reflect.ValueOf(strings.Split(s, "°")).Convert(typ))
+	case typ.Kind() == reflect.String:
+		out.SetString(s)
+	case typ.Kind() == reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		out.SetBool(b)
+	case out.CanInt():
+		var i int64
+		i, err = strconv.ParseInt(s, 10, typ.Bits())
+		out.SetInt(i)
+	case out.CanUint():
+		var u uint64
+		u, err = strconv.ParseUint(s, 10, typ.Bits())
+		out.SetUint(u)
+	case out.CanFloat():
+		var f float64
+		f, err = strconv.ParseFloat(s, typ.Bits())
+		out.SetFloat(f)
+	default:
+		return nil, fmt.Errorf("type %s is not supported", typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore %s value, the following error occured: %s", typ, err)
 	}
+
+	return out.Interface(), nil
 }
@@ -3,6 +3,7 @@ package encryption
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestConvert(t *testing.T) {
@@ -52,6 +53,33 @@ func TestConvert(t *testing.T) {
 			want:    int16(16),
 			wantErr: false,
 		},
+		{
+			name: "convert int16 outside the int8 range",
+			args: args{
+				s: "-300",
+				t: reflect.ValueOf(int16(0)),
+			},
+			want:    int16(-300),
+			wantErr: false,
+		},
+		{
+			name: "convert uint32 outside the uint8 range",
+			args: args{
+				s: "70000",
+				t: reflect.ValueOf(uint32(0)),
+			},
+			want:    uint32(70000),
+			wantErr: false,
+		},
+		{
+			name: "convert int64 outside the int8 range",
+			args: args{
+				s: "-9223372036854775808",
+				t: reflect.ValueOf(int64(0)),
+			},
+			want:    int64(-9223372036854775808),
+			wantErr: false,
+		},
 		{
 			name: "convert uint16",
 			args: args{
@@ -160,6 +188,15 @@ func TestConvert(t *testing.T) {
 			want:    bool(true),
 			wantErr: false,
 		},
+		{
+			name: "convert time.Time",
+			args: args{
+				s: "2021-03-04T05:06:07.000000008Z",
+				t: reflect.ValueOf(time.Time{}),
+			},
+			want:    time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC),
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
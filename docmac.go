@@ -0,0 +1,162 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+//Define document MAC constants
+const (
+	DocumentMACField = "_mac"
+	documentMACLabel = "globe-protocol/encryption document mac"
+)
+
+//Single field covered by the document MAC
+type macField struct {
+	name      string
+	encrypted bool
+	value     []byte
+}
+
+//Collects all fields of a document so that they can be authenticated together
+type documentMAC struct {
+	fields []macField
+}
+
+func (d *documentMAC) add(name string, encrypted bool, value []byte) {
+	d.fields = append(d.fields, macField{name: name, encrypted: encrypted, value: value})
+}
+
+//Calculate MAC over all fields sorted by name, names and values are length prefixed so that fields cannot be shifted
+func (d *documentMAC) sum(key []byte) []byte {
+	sort.Slice(d.fields, func(i, j int) bool {
+		return d.fields[i].name < d.fields[j].name
+	})
+
+	mac := hmac.New(sha256.New, key)
+	count := make([]byte, 4)
+	binary.BigEndian.PutUint32(count, uint32(len(d.fields)))
+	mac.Write(count)
+
+	for _, f := range d.fields {
+		buf := binary.BigEndian.AppendUint32(nil, uint32(len(f.name)))
+		buf = append(buf, f.name...)
+		if f.encrypted {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(f.value)))
+		mac.Write(buf)
+		mac.Write(f.value)
+	}
+
+	return mac.Sum(nil)
+}
+
+//Get a copy of the service that adds a MAC over all fields to encrypted structs and verifies it in Decrypt
+func (e *encryptionService) WithDocumentMAC() (EncryptionService, error) {
+	if _, err := e.deriveKey(documentMACLabel); err != nil {
+		return nil, err
+	}
+
+	service := *e
+	service.documentMAC = true
+
+	return &service, nil
+}
+
+//Add MAC over the plaintext and ciphertext of every field of the struct to the output object
+func (e *encryptionService) addDocumentMAC(returnObj map[string]interface{}, object reflect.Value, fieldTagNames []string) error {
+	if !e.documentMAC {
+		return nil
+	}
+
+	key, err := e.deriveKey(documentMACLabel)
+	if err != nil {
+		return err
+	}
+
+	doc := &documentMAC{}
+	plan := planFor(object.Type())
+	for i := range plan.fields {
+		fieldName, err := plan.fields[i].name(fieldTagNames)
+		if err != nil {
+			return err
+		}
+		if fieldName == DocumentMACField {
+			return fmt.Errorf("field name %s is reserved for the document MAC", DocumentMACField)
+		}
+
+		switch val := returnObj[fieldName].(type) {
+		case []byte:
+			doc.add(fieldName, true, val)
+		case string:
+			doc.add(fieldName, false, []byte(val))
+		}
+
+		//Index tokens are covered as well so that they cannot be swapped between documents
+		if tokens, ok := returnObj[fieldName+SearchFieldSuffix].([]string); ok {
+			doc.add(fieldName+SearchFieldSuffix, false, macStrings(tokens))
+		}
+		if token, ok := returnObj[fieldName+RangeFieldSuffix].(string); ok {
+			doc.add(fieldName+RangeFieldSuffix, false, []byte(token))
+		}
+	}
+
+	returnObj[DocumentMACField] = doc.sum(key)
+
+	return nil
+}
+
+//Serialize a list of index tokens, every token is length prefixed
+func macStrings(values []string) []byte {
+	b := []byte{}
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(v)))
+		b = append(b, v...)
+	}
+
+	return b
+}
+
+//Get the MAC value of an index field read by Decrypt, search tokens are a list and range tokens a string
+func indexFieldValue(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String {
+		return macStrings(v.Convert(reflect.TypeOf([]string{})).Interface().([]string))
+	}
+
+	return []byte(fmt.Sprint(v))
+}
+
+//Whether an index field read by Decrypt holds no tokens, which is how the index of an omitted value is stored
+func isEmptyIndex(v reflect.Value) bool {
+	if v.Kind() == reflect.Slice {
+		return v.Len() == 0
+	}
+
+	return v.IsZero()
+}
+
+//Compare the stored document MAC with the MAC of the fields read by Decrypt
+func (e *encryptionService) verifyDocumentMAC(doc *documentMAC, stored []byte) error {
+	if stored == nil {
+		return fmt.Errorf("document does not contain a %s field", DocumentMACField)
+	}
+
+	key, err := e.deriveKey(documentMACLabel)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(doc.sum(key), stored) {
+		return errors.New("document MAC does not match, fields were modified, added or removed")
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package encryption
+
+import (
+	"reflect"
+	"testing"
+)
+
+type stringfloatboolMAC struct {
+	String    string `bson:"String" encrypted:"false"`
+	Float64   []byte `bson:"Float64"`
+	Bool      []byte `bson:"Bool"`
+	StringArr []byte `bson:"StringArr"`
+	EmptyVal  []byte `bson:"EmptyVal"`
+	MAC       []byte `bson:"_mac"`
+}
+
+func Test_encryptionService_WithDocumentMAC(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(obj *stringfloatboolMAC)
+		wantErr bool
+	}{
+		{
+			name:    "untouched document",
+			tamper:  func(obj *stringfloatboolMAC) {},
+			wantErr: false,
+		},
+		{
+			name: "modified plain field",
+			tamper: func(obj *stringfloatboolMAC) {
+				obj.String = "456Test"
+			},
+			wantErr: true,
+		},
+		{
+			name: "removed encrypted field",
+			tamper: func(obj *stringfloatboolMAC) {
+				obj.EmptyVal = nil
+			},
+			wantErr: true,
+		},
+		{
+			name: "swapped encrypted fields",
+			tamper: func(obj *stringfloatboolMAC) {
+				obj.Float64, obj.Bool = obj.Bool, obj.Float64
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing MAC",
+			tamper: func(obj *stringfloatboolMAC) {
+				obj.MAC = nil
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewEncryptionService([]byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}).(DocumentAuthenticator).WithDocumentMAC()
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while enabling document MAC", err)
+			}
+
+			data := stringfloatbool{String: "123Test", Float64: 64.64, Bool: true}
+			encryptedData, err := e.EncryptToInterface(data)
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while encrypting object", err)
+			}
+
+			encryptedObj := stringfloatboolMAC{
+				String:    encryptedData["String"].(string),
+				Float64:   encryptedData["Float64"].([]byte),
+				Bool:      encryptedData["Bool"].([]byte),
+				StringArr: encryptedData["StringArr"].([]byte),
+				EmptyVal:  encryptedData["EmptyVal"].([]byte),
+				MAC:       encryptedData[DocumentMACField].([]byte),
+			}
+			tt.tamper(&encryptedObj)
+
+			decryptedData, err := e.Decrypt(encryptedObj, stringfloatbool{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(decryptedData, &stringfloatbool{String: "123Test", Float64: 64.64, Bool: true, StringArr: []string{""}}) {
+				t.Errorf("decrypted body = %+v\n want = %+v\n", decryptedData, data)
+			}
+		})
+	}
+}
+
+type indexedMAC struct {
+	Name   string `bson:"name" search:"prefix"`
+	Salary int    `bson:"salary" range:"width=1000"`
+}
+
+type indexedMACEnc struct {
+	Name        []byte   `bson:"name"`
+	NameSearch  []string `bson:"name_search"`
+	Salary      []byte   `bson:"salary"`
+	SalaryRange string   `bson:"salary_range"`
+	MAC         []byte   `bson:"_mac"`
+}
+
+//Index tokens are covered by the MAC, swapping them with the tokens of another document is detected
+func Test_encryptionService_WithDocumentMAC_IndexTokens(t *testing.T) {
+	e, err := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef")).(DocumentAuthenticator).WithDocumentMAC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypt := func(model indexedMAC) indexedMACEnc {
+		encrypted, err := e.EncryptToInterface(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return indexedMACEnc{
+			Name:        encrypted["name"].([]byte),
+			NameSearch:  encrypted["name_search"].([]string),
+			Salary:      encrypted["salary"].([]byte),
+			SalaryRange: encrypted["salary_range"].(string),
+			MAC:         encrypted[DocumentMACField].([]byte),
+		}
+	}
+	alice, bob := encrypt(indexedMAC{Name: "alice", Salary: 45250}), encrypt(indexedMAC{Name: "bob", Salary: 90000})
+
+	tests := []struct {
+		name    string
+		tamper  func(doc *indexedMACEnc)
+		wantErr bool
+	}{
+		{name: "untouched document", tamper: func(doc *indexedMACEnc) {}},
+		{name: "swapped search tokens", tamper: func(doc *indexedMACEnc) { doc.NameSearch = bob.NameSearch }, wantErr: true},
+		{name: "swapped range token", tamper: func(doc *indexedMACEnc) { doc.SalaryRange = bob.SalaryRange }, wantErr: true},
+		{name: "removed search tokens", tamper: func(doc *indexedMACEnc) { doc.NameSearch = nil }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := alice
+			tt.tamper(&doc)
+
+			decrypted, err := e.Decrypt(doc, indexedMAC{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("encryptionService.Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(decrypted, &indexedMAC{Name: "alice", Salary: 45250}) {
+				t.Errorf("decrypted body = %+v", decrypted)
+			}
+		})
+	}
+}
+
+//Omitted empty values have no index tokens, on both sides of the MAC
+func Test_encryptionService_WithDocumentMAC_OmittedIndex(t *testing.T) {
+	type omittedIndex struct {
+		Id   string `bson:"_id" encrypted:"false"`
+		Nick string `bson:"nick" encrypted:",omitempty" search:"prefix"`
+		Age  int    `bson:"age" encrypted:",omitempty" range:"width=10"`
+	}
+	type omittedIndexEnc struct {
+		Id         string   `bson:"_id" encrypted:"false"`
+		Nick       []byte   `bson:"nick"`
+		NickSearch []string `bson:"nick_search"`
+		Age        []byte   `bson:"age"`
+		AgeRange   string   `bson:"age_range"`
+		MAC        []byte   `bson:"_mac"`
+	}
+
+	e, err := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef")).(DocumentAuthenticator).WithDocumentMAC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypt := func(model omittedIndex) omittedIndexEnc {
+		encrypted, err := e.EncryptToInterface(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		doc := omittedIndexEnc{Id: encrypted["_id"].(string), MAC: encrypted[DocumentMACField].([]byte)}
+		doc.Nick, _ = encrypted["nick"].([]byte)
+		doc.NickSearch, _ = encrypted["nick_search"].([]string)
+		doc.Age, _ = encrypted["age"].([]byte)
+		doc.AgeRange, _ = encrypted["age_range"].(string)
+
+		return doc
+	}
+
+	empty := encrypt(omittedIndex{Id: "1"})
+	if decrypted, err := e.Decrypt(empty, omittedIndex{}); err != nil || !reflect.DeepEqual(decrypted, &omittedIndex{Id: "1"}) {
+		t.Errorf("encryptionService.Decrypt() of omitted indexed fields = %+v, %v", decrypted, err)
+	}
+
+	filled := encrypt(omittedIndex{Id: "1", Nick: "al", Age: 42})
+	if _, err := e.Decrypt(filled, omittedIndex{}); err != nil {
+		t.Errorf("encryptionService.Decrypt() error = %v", err)
+	}
+
+	//Removing the tokens of a value that is present is still detected
+	filled.NickSearch = nil
+	if _, err := e.Decrypt(filled, omittedIndex{}); err == nil {
+		t.Errorf("encryptionService.Decrypt() with removed search tokens should fail")
+	}
+
+	//Adding tokens to an omitted value is detected as well
+	empty.AgeRange = filled.AgeRange
+	if _, err := e.Decrypt(empty, omittedIndex{}); err == nil {
+		t.Errorf("encryptionService.Decrypt() with added range token should fail")
+	}
+}
@@ -4,18 +4,47 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 )
 
 //Define all outlier types
 const (
 	StringArr = "[]string"
+	Time      = "time.Time"
 )
 
 func Encode(value reflect.Value) string {
-	switch value.Type().String() {
+	return encodeType(value.Type().String(), value)
+}
+
+//Encode value of the type with the given name, the name is passed so that it can be looked up once per struct type
+func encodeType(typeName string, value reflect.Value) string {
+	switch typeName {
 	case StringArr:
 		return strings.Join(value.Interface().([]string), "°")
+	case Time:
+		return value.Interface().(time.Time).Format(time.RFC3339Nano)
 	default:
 		return fmt.Sprint(value)
 	}
 }
+
+//Encode value of a basic kind, time.Time, []byte or []string so that decodeValue can restore it exactly
+func encodeValue(value reflect.Value) (string, error) {
+	switch {
+	case value.Type() == reflect.TypeOf(time.Time{}):
+		return value.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8:
+		return string(value.Bytes()), nil
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		return strings.Join(value.Convert(reflect.TypeOf([]string{})).Interface().([]string), "°"), nil
+	}
+
+	switch value.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return fmt.Sprint(value), nil
+	default:
+		return "", fmt.Errorf("type %s is not supported", value.Type())
+	}
+}
@@ -3,6 +3,7 @@ package encryption
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestEncode(t *testing.T) {
@@ -28,6 +29,13 @@ func TestEncode(t *testing.T) {
 			},
 			want: "test met iets anders",
 		},
+		{
+			name: "time.Time",
+			args: args{
+				value: reflect.ValueOf(time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC)),
+			},
+			want: "2021-03-04T05:06:07.000000008Z",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
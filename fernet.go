@@ -0,0 +1,227 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+//Define Fernet format constants, see https://github.com/fernet/spec
+const (
+	FernetVersion   byte = 0x80
+	FernetClockSkew      = 60 * time.Second
+	fernetKeySize        = 32
+	fernetHeaderLen      = 1 + 8 + aes.BlockSize
+	fernetMinLen         = fernetHeaderLen + aes.BlockSize + sha256.Size
+)
+
+//Fernet tokens are encrypted with the first key and can be decrypted with any of the keys, like MultiFernet
+type Fernet struct {
+	keys  [][]byte
+	clock func() time.Time
+}
+
+//Create a Fernet instance from one or more URL-safe base64 encoded 32 byte keys, the first key is used for encryption
+func NewFernet(keys ...string) (*Fernet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one fernet key is required")
+	}
+
+	f := &Fernet{}
+	for _, key := range keys {
+		b, err := base64.URLEncoding.DecodeString(key)
+		if err != nil || len(b) != fernetKeySize {
+			return nil, errors.New("fernet key must be 32 url-safe base64-encoded bytes")
+		}
+		f.keys = append(f.keys, b)
+	}
+
+	return f, nil
+}
+
+//Generate a new random Fernet key
+func GenerateFernetKey() (string, error) {
+	key := make([]byte, fernetKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+
+	return base64.URLEncoding.EncodeToString(key), nil
+}
+
+//Get a copy that uses the given clock instead of time.Now
+func (f *Fernet) WithClock(clock func() time.Time) *Fernet {
+	c := *f
+	c.clock = clock
+
+	return &c
+}
+
+func (f *Fernet) now() time.Time {
+	if f.clock == nil {
+		return time.Now()
+	}
+
+	return f.clock()
+}
+
+//Encrypt string to a Fernet token
+func (f *Fernet) EncryptStr(str string) (string, error) {
+	return f.EncryptByt([]byte(str))
+}
+
+//Encrypt []byte to a Fernet token
+func (f *Fernet) EncryptByt(b []byte) (string, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	return f.encrypt(f.keys[0], b, f.now(), iv)
+}
+
+func (f *Fernet) encrypt(key []byte, plain []byte, timestamp time.Time, iv []byte) (string, error) {
+	block, err := aes.NewCipher(key[16:])
+	if err != nil {
+		return "", fmt.Errorf("failed to create cipher, external aes package returned the following error: %s", err)
+	}
+
+	//PKCS7 padding
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	padded := append(append([]byte{}, plain...), make([]byte, padding)...)
+	for i := len(plain); i < len(padded); i++ {
+		padded[i] = byte(padding)
+	}
+
+	token := []byte{FernetVersion}
+	token = binary.BigEndian.AppendUint64(token, uint64(timestamp.Unix()))
+	token = append(token, iv...)
+
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+	token = append(token, ciphertext...)
+
+	mac := hmac.New(sha256.New, key[:16])
+	mac.Write(token)
+	token = mac.Sum(token)
+
+	return base64.URLEncoding.EncodeToString(token), nil
+}
+
+//Decrypt Fernet token to a string, tokens older than ttl are rejected unless ttl is 0
+func (f *Fernet) DecryptStr(token string, ttl time.Duration) (string, error) {
+	b, err := f.DecryptByt(token, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+//Decrypt Fernet token to []byte, tokens older than ttl or from the future are rejected unless ttl is 0
+func (f *Fernet) DecryptByt(token string, ttl time.Duration) ([]byte, error) {
+	raw, key, timestamp, err := f.verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	//Like the reference implementation the timestamp is only checked when a ttl is given
+	if ttl > 0 {
+		now := f.now()
+		if timestamp.Add(ttl).Before(now) {
+			return nil, ErrTokenExpired
+		}
+		if now.Add(FernetClockSkew).Before(timestamp) {
+			return nil, ErrTokenNotYetValid
+		}
+	}
+
+	return f.decrypt(key, raw)
+}
+
+//Get the time a token was created, the token is authenticated but not decrypted
+func (f *Fernet) ExtractTimestamp(token string) (time.Time, error) {
+	_, _, timestamp, err := f.verify(token)
+
+	return timestamp, err
+}
+
+//Decrypt token with any of the keys and encrypt it again with the first key, keeping the original timestamp
+func (f *Fernet) Rotate(token string) (string, error) {
+	raw, key, timestamp, err := f.verify(token)
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := f.decrypt(key, raw)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	return f.encrypt(f.keys[0], plain, timestamp, iv)
+}
+
+//Decode token and find the key with which the HMAC is valid
+func (f *Fernet) verify(token string) ([]byte, []byte, time.Time, error) {
+	raw, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, nil, time.Time{}, errors.New("fernet token is not valid url-safe base64")
+	}
+
+	if len(raw) < fernetMinLen || (len(raw)-fernetHeaderLen-sha256.Size)%aes.BlockSize != 0 {
+		return nil, nil, time.Time{}, errors.New("fernet token has an invalid length")
+	}
+	if raw[0] != FernetVersion {
+		return nil, nil, time.Time{}, fmt.Errorf("fernet version %#x is not supported", raw[0])
+	}
+
+	signed, signature := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+	for _, key := range f.keys {
+		mac := hmac.New(sha256.New, key[:16])
+		mac.Write(signed)
+		if hmac.Equal(mac.Sum(nil), signature) {
+			return raw, key, time.Unix(int64(binary.BigEndian.Uint64(raw[1:9])), 0), nil
+		}
+	}
+
+	return nil, nil, time.Time{}, errors.New("fernet token has an invalid signature")
+}
+
+func (f *Fernet) decrypt(key []byte, raw []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key[16:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher, external aes package returned the following error: %s", err)
+	}
+
+	iv := raw[9:fernetHeaderLen]
+	ciphertext := raw[fernetHeaderLen : len(raw)-sha256.Size]
+
+	plain := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, ciphertext)
+
+	//Remove PKCS7 padding
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errors.New("fernet token has invalid padding")
+	}
+	for _, b := range plain[len(plain)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("fernet token has invalid padding")
+		}
+	}
+
+	return plain[:len(plain)-padding], nil
+}
@@ -0,0 +1,242 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+//Vector from generate.json and verify.json of https://github.com/fernet/spec
+const (
+	fernetSpecSecret = "cw_0x689RpI-jtRR7oE8h_eQsKImvJapLeSbXpwF4e4="
+	fernetSpecToken  = "gAAAAAAdwJ6wAAECAwQFBgcICQoLDA0ODy021cpGVWKZ_eEwCGM4BLLF_5CV9dOPmrhuVUPgJobwOz7JcbmrR64jVmpU4IwqDA=="
+	fernetSpecSrc    = "hello"
+)
+
+func fernetSpecTime(t *testing.T, s string) time.Time {
+	now, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return now
+}
+
+//Sign modified token again so that the modification itself is tested instead of the HMAC
+func resignFernet(t *testing.T, raw []byte) string {
+	key, err := base64.URLEncoding.DecodeString(fernetSpecSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mac := hmac.New(sha256.New, key[:16])
+	mac.Write(raw)
+
+	return base64.URLEncoding.EncodeToString(mac.Sum(raw))
+}
+
+func Test_Fernet_Generate(t *testing.T) {
+	f, err := NewFernet(fernetSpecSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iv := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	got, err := f.encrypt(f.keys[0], []byte(fernetSpecSrc), fernetSpecTime(t, "1985-10-26T01:20:00-07:00"), iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != fernetSpecToken {
+		t.Errorf("Fernet.encrypt() = %v, want %v", got, fernetSpecToken)
+	}
+}
+
+func Test_Fernet_Verify(t *testing.T) {
+	key, err := base64.URLEncoding.DecodeString(fernetSpecSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.URLEncoding.DecodeString(fernetSpecToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		now     string
+		ttl     time.Duration
+		wantErr bool
+	}{
+		{
+			name:    "valid token",
+			token:   func() string { return fernetSpecToken },
+			now:     "1985-10-26T01:20:01-07:00",
+			ttl:     60 * time.Second,
+			wantErr: false,
+		},
+		{
+			name:    "valid token without ttl",
+			token:   func() string { return fernetSpecToken },
+			now:     "2015-10-26T01:20:01-07:00",
+			ttl:     0,
+			wantErr: false,
+		},
+		{
+			name:    "expired ttl",
+			token:   func() string { return fernetSpecToken },
+			now:     "1985-10-26T01:21:31-07:00",
+			ttl:     60 * time.Second,
+			wantErr: true,
+		},
+		{
+			name:    "far-future timestamp",
+			token:   func() string { return fernetSpecToken },
+			now:     "1985-10-26T01:18:00-07:00",
+			ttl:     60 * time.Second,
+			wantErr: true,
+		},
+		{
+			name:    "far-future timestamp without ttl",
+			token:   func() string { return fernetSpecToken },
+			now:     "1985-10-26T01:18:00-07:00",
+			ttl:     0,
+			wantErr: false,
+		},
+		{
+			name: "incorrect mac",
+			token: func() string {
+				b := append([]byte{}, raw...)
+				b[len(b)-1] ^= 1
+				return base64.URLEncoding.EncodeToString(b)
+			},
+			now:     "1985-10-26T01:20:01-07:00",
+			ttl:     60 * time.Second,
+			wantErr: true,
+		},
+		{
+			name:    "too short",
+			token:   func() string { return base64.URLEncoding.EncodeToString(raw[:20]) },
+			now:     "1985-10-26T01:20:01-07:00",
+			ttl:     60 * time.Second,
+			wantErr: true,
+		},
+		{
+			name:    "invalid base64",
+			token:   func() string { return "%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%" },
+			now:     "1985-10-26T01:20:01-07:00",
+			ttl:     60 * time.Second,
+			wantErr: true,
+		},
+		{
+			name: "payload size not multiple of block size",
+			token: func() string {
+				b := append([]byte{}, raw[:len(raw)-sha256.Size-1]...)
+				return resignFernet(t, b)
+			},
+			now:     "1985-10-26T01:20:01-07:00",
+			ttl:     60 * time.Second,
+			wantErr: true,
+		},
+		{
+			name: "payload padding error",
+			token: func() string {
+				block, _ := aes.NewCipher(key[16:])
+				b := append([]byte{}, raw[:fernetHeaderLen]...)
+				ciphertext := make([]byte, aes.BlockSize)
+				padded := []byte("hello\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+				cipher.NewCBCEncrypter(block, raw[9:fernetHeaderLen]).CryptBlocks(ciphertext, padded)
+				return resignFernet(t, append(b, ciphertext...))
+			},
+			now:     "1985-10-26T01:20:01-07:00",
+			ttl:     60 * time.Second,
+			wantErr: true,
+		},
+		{
+			name: "unknown version",
+			token: func() string {
+				b := append([]byte{}, raw[:len(raw)-sha256.Size]...)
+				b[0] = 0x81
+				return resignFernet(t, b)
+			},
+			now:     "1985-10-26T01:20:01-07:00",
+			ttl:     60 * time.Second,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFernet(fernetSpecSecret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := fernetSpecTime(t, tt.now)
+			f = f.WithClock(func() time.Time { return now })
+
+			got, err := f.DecryptStr(tt.token(), tt.ttl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Fernet.DecryptStr() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && got != fernetSpecSrc {
+				t.Errorf("Fernet.DecryptStr() = %v, want %v", got, fernetSpecSrc)
+			}
+		})
+	}
+}
+
+func Test_Fernet_Rotate(t *testing.T) {
+	newKey, err := GenerateFernetKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := NewFernet(fernetSpecSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	multi, err := NewFernet(newKey, fernetSpecSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := NewFernet(newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Old tokens can still be read during rotation
+	if _, err := multi.DecryptStr(fernetSpecToken, 0); err != nil {
+		t.Errorf("Fernet.DecryptStr() with old key error = %v", err)
+	}
+
+	rotated, err := multi.Rotate(fernetSpecToken)
+	if err != nil {
+		t.Fatalf("Fernet.Rotate() error = %v", err)
+	}
+
+	if _, err := old.DecryptStr(rotated, 0); err == nil {
+		t.Errorf("expected rotated token to not be readable with old key")
+	}
+
+	got, err := current.DecryptStr(rotated, 0)
+	if err != nil {
+		t.Fatalf("Fernet.DecryptStr() rotated token error = %v", err)
+	}
+	if got != fernetSpecSrc {
+		t.Errorf("Fernet.DecryptStr() = %v, want %v", got, fernetSpecSrc)
+	}
+
+	timestamp, err := current.ExtractTimestamp(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !timestamp.Equal(fernetSpecTime(t, "1985-10-26T01:20:00-07:00")) {
+		t.Errorf("Fernet.Rotate() did not keep timestamp, got %v", timestamp)
+	}
+}
@@ -0,0 +1,179 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//Define encrypted tag options
+const (
+	FieldModeRandom        = "random"
+	FieldModeDeterministic = "deterministic"
+	fieldKeyLabel          = "globe-protocol/encryption field key "
+	fieldDefaultKeyLabel   = "globe-protocol/encryption field default key"
+	fieldNonceLabel        = "globe-protocol/encryption deterministic nonce"
+)
+
+//Parsed options of the encrypted tag of a single struct field
+type fieldOptions struct {
+	//Name of the subkey derived from the service key, empty for the service key itself
+	key           string
+	deterministic bool
+	omitEmpty     bool
+	//Name of the plain field whose value is authenticated together with the field
+	aad      string
+	aadIndex int
+}
+
+//Parse encrypted tag, e.g. `encrypted:"true,key=pii,mode=deterministic,omitempty,aad=tenant_id"`
+func parseEncryptedTag(tag string) (bool, fieldOptions, error) {
+	options := fieldOptions{}
+	parts := strings.Split(tag, ",")
+
+	switch parts[0] {
+	case "", "true":
+	case "false":
+		if len(parts) > 1 {
+			return false, options, errors.New("options cannot be used on fields that are not encrypted")
+		}
+		return false, options, nil
+	default:
+		return false, options, fmt.Errorf("%q should be true or false", parts[0])
+	}
+
+	seen := map[string]bool{}
+	for _, option := range parts[1:] {
+		key, value, hasValue := strings.Cut(option, "=")
+		if seen[key] {
+			return true, options, fmt.Errorf("option %s is set more than once", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "key":
+			if !isOptionName(value) {
+				return true, options, fmt.Errorf("key name %q should only contain letters, digits, '-', '_' and '.'", value)
+			}
+			options.key = value
+		case "mode":
+			if value != FieldModeRandom && value != FieldModeDeterministic {
+				return true, options, fmt.Errorf("%q is not a supported mode, use %s or %s", value, FieldModeRandom, FieldModeDeterministic)
+			}
+			options.deterministic = value == FieldModeDeterministic
+		case "omitempty":
+			if hasValue {
+				return true, options, errors.New("option omitempty does not take a value")
+			}
+			options.omitEmpty = true
+		case "aad":
+			if value == "" {
+				return true, options, errors.New("option aad requires the name of a field")
+			}
+			options.aad = value
+		default:
+			return true, options, fmt.Errorf("%s is not a supported option", key)
+		}
+	}
+
+	return true, options, nil
+}
+
+func isOptionName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+//Whether the field is sealed with its own AEAD instead of the one of the service
+func (o *fieldOptions) sealsOwn() bool {
+	return o.key != "" || o.deterministic || o.aad != ""
+}
+
+//Get the AEAD and key of a field with its own key, mode or associated data.
+//The key is derived from the pinned derive version of a keyring, so these fields keep their ciphertexts and do not move to the current key when a keyring is rotated.
+func (e *encryptionService) fieldGCM(f *fieldPlan) (cipher.AEAD, []byte, error) {
+	//Fields without a key option get a subkey of their own as well, so the derive key itself is never used with AES-GCM
+	label := fieldDefaultKeyLabel
+	if f.options.key != "" {
+		label = fieldKeyLabel + f.options.key
+	}
+
+	key, err := e.deriveKey(label)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encrypted tag options of field %s require a service created with a symmetric key", f.field.Name)
+	}
+
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return aesGCM, key, nil
+}
+
+//Encrypt the encoded value of a field, fields without key, mode or aad options are encrypted like EncryptStr
+func (e *encryptionService) sealField(s sealer, f *fieldPlan, str string, aad []byte) ([]byte, error) {
+	if !f.options.sealsOwn() {
+		return s.seal([]byte(str))
+	}
+
+	fieldGCM, key, err := e.fieldGCM(f)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, fieldGCM.NonceSize())
+	if f.options.deterministic {
+		//Synthetic nonce, equal values with equal associated data get equal ciphertexts
+		mac := hmac.New(sha256.New, deriveKeyFrom(key, fieldNonceLabel))
+		mac.Write(binary.BigEndian.AppendUint32(nil, uint32(len(aad))))
+		mac.Write(aad)
+		mac.Write([]byte(str))
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return fieldGCM.Seal(nonce, nonce, []byte(str), aad), nil
+}
+
+//Decrypt a field encrypted by sealField
+func (e *encryptionService) openField(s sealer, f *fieldPlan, val []byte, aad []byte) (string, error) {
+	if !f.options.sealsOwn() {
+		return e.getPlainText(val, s)
+	}
+
+	fieldGCM, _, err := e.fieldGCM(f)
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := fieldGCM.NonceSize()
+	if len(val) < nonceSize {
+		return "", errors.New("ciphertext is too short")
+	}
+
+	plainbytes, err := fieldGCM.Open(nil, val[:nonceSize], val[nonceSize:], aad)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plainbytes), nil
+}
@@ -0,0 +1,266 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"reflect"
+	"testing"
+)
+
+type taggedModel struct {
+	Tenant string `bson:"tenant_id" encrypted:"false"`
+	Email  string `bson:"email" encrypted:"true,key=pii,mode=deterministic,aad=tenant_id"`
+	Phone  string `bson:"phone" encrypted:"true,key=pii"`
+	Note   string `bson:"note" encrypted:",omitempty"`
+	Age    int    `bson:"age" encrypted:"true,omitempty"`
+}
+
+type taggedModelEnc struct {
+	Tenant string `bson:"tenant_id" encrypted:"false"`
+	Email  []byte `bson:"email"`
+	Phone  []byte `bson:"phone"`
+	Note   []byte `bson:"note"`
+	Age    []byte `bson:"age"`
+}
+
+func Test_parseEncryptedTag(t *testing.T) {
+	tests := []struct {
+		tag           string
+		wantEncrypted bool
+		want          fieldOptions
+		wantErr       bool
+	}{
+		{tag: "", wantEncrypted: true},
+		{tag: "true", wantEncrypted: true},
+		{tag: "false", wantEncrypted: false},
+		{tag: "true,key=pii,mode=deterministic,omitempty,aad=tenant_id", wantEncrypted: true, want: fieldOptions{key: "pii", deterministic: true, omitEmpty: true, aad: "tenant_id"}},
+		{tag: ",omitempty", wantEncrypted: true, want: fieldOptions{omitEmpty: true}},
+		{tag: "true,mode=random", wantEncrypted: true},
+		{tag: "yes", wantErr: true},
+		{tag: "false,omitempty", wantErr: true},
+		{tag: "true,compress", wantEncrypted: true, wantErr: true},
+		{tag: "true,mode=ecb", wantEncrypted: true, wantErr: true},
+		{tag: "true,key=", wantEncrypted: true, wantErr: true},
+		{tag: "true,key=a b", wantEncrypted: true, wantErr: true},
+		{tag: "true,key=a,key=b", wantEncrypted: true, wantErr: true},
+		{tag: "true,omitempty=yes", wantEncrypted: true, wantErr: true},
+		{tag: "true,aad", wantEncrypted: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			encrypted, options, err := parseEncryptedTag(tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEncryptedTag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if encrypted != tt.wantEncrypted {
+				t.Errorf("parseEncryptedTag() encrypted = %v, want %v", encrypted, tt.wantEncrypted)
+			}
+			if !tt.wantErr && options != tt.want {
+				t.Errorf("parseEncryptedTag() options = %+v, want %+v", options, tt.want)
+			}
+		})
+	}
+}
+
+func Test_encryptionService_EncryptToInterface_TagOptions(t *testing.T) {
+	e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+	model := taggedModel{Tenant: "acme", Email: "alice@example.com", Phone: "+31 6 12345678"}
+
+	first, err := e.EncryptToInterface(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := e.EncryptToInterface(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(first["email"].([]byte), second["email"].([]byte)) {
+		t.Errorf("deterministic field encrypted to different ciphertexts")
+	}
+	if bytes.Equal(first["phone"].([]byte), second["phone"].([]byte)) {
+		t.Errorf("random field encrypted to equal ciphertexts")
+	}
+	if _, err := e.DecryptByt(first["phone"].([]byte)); err == nil {
+		t.Errorf("field with key option could be decrypted with the service key")
+	}
+	for _, name := range []string{"note", "age"} {
+		if _, ok := first[name]; ok {
+			t.Errorf("empty omitempty field %s is in the output", name)
+		}
+	}
+
+	other, err := e.EncryptToInterface(taggedModel{Tenant: "globex", Email: model.Email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first["email"].([]byte), other["email"].([]byte)) {
+		t.Errorf("deterministic field of other tenant encrypted to the same ciphertext")
+	}
+
+	encrypted := taggedModelEnc{
+		Tenant: first["tenant_id"].(string),
+		Email:  first["email"].([]byte),
+		Phone:  first["phone"].([]byte),
+	}
+	decrypted, err := e.Decrypt(encrypted, taggedModel{})
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if got := reflect.Indirect(reflect.ValueOf(decrypted)).Interface(); !reflect.DeepEqual(got, model) {
+		t.Errorf("Decrypt() = %+v, want %+v", got, model)
+	}
+
+	//Moving the value to another tenant breaks the associated data
+	encrypted.Tenant = "globex"
+	if _, err := e.Decrypt(encrypted, taggedModel{}); err == nil {
+		t.Errorf("Decrypt() with other aad value should fail")
+	}
+}
+
+func Test_encryptionService_TagOptions_Invalid(t *testing.T) {
+	e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+
+	type unknownOption struct {
+		Name string `bson:"name" encrypted:"true,compress"`
+		Age  int    `bson:"age" encrypted:"true,aad=missing"`
+	}
+	type encryptedAAD struct {
+		Tenant string `bson:"tenant_id"`
+		Email  string `bson:"email" encrypted:"true,aad=tenant_id"`
+	}
+
+	tests := []struct {
+		name  string
+		model interface{}
+		want  []string
+	}{
+		{name: "unknown option", model: unknownOption{}, want: []string{"field Name: compress is not a supported option", "field Age: aad field missing does not exist"}},
+		{name: "encrypted aad field", model: encryptedAAD{}, want: []string{"aad field tenant_id has to be tagged encrypted:\"false\""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.EncryptToInterface(tt.model)
+			if err == nil {
+				t.Fatalf("EncryptToInterface() should fail")
+			}
+			for _, want := range tt.want {
+				if !bytes.Contains([]byte(err.Error()), []byte(want)) {
+					t.Errorf("EncryptToInterface() error = %v, want it to contain %q", err, want)
+				}
+			}
+
+			if _, err := e.EncryptToJSON(tt.model); err == nil {
+				t.Errorf("EncryptToJSON() should fail")
+			}
+			if _, err := e.Decrypt(taggedModelEnc{}, tt.model); err == nil {
+				t.Errorf("Decrypt() should fail")
+			}
+		})
+	}
+}
+
+func Test_encryptionService_TagOptions_PublicKey(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewPublicKeyEncryptionService(key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.EncryptToInterface(taggedModel{Tenant: "acme", Email: "alice@example.com"}); err == nil {
+		t.Errorf("EncryptToInterface() with key options should fail without a symmetric key")
+	}
+}
+
+//Omitted fields are not part of the document MAC, setting them afterwards is detected
+func Test_encryptionService_TagOptions_DocumentMAC(t *testing.T) {
+	e, err := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef")).(DocumentAuthenticator).WithDocumentMAC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type taggedModelMAC struct {
+		Tenant string `bson:"tenant_id" encrypted:"false"`
+		Email  []byte `bson:"email"`
+		Phone  []byte `bson:"phone"`
+		Note   []byte `bson:"note"`
+		Age    []byte `bson:"age"`
+		MAC    []byte `bson:"_mac"`
+	}
+
+	encrypted, err := e.EncryptToInterface(taggedModel{Tenant: "acme", Email: "alice@example.com", Note: "note"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := taggedModelMAC{
+		Tenant: encrypted["tenant_id"].(string),
+		Email:  encrypted["email"].([]byte),
+		Phone:  encrypted["phone"].([]byte),
+		Note:   encrypted["note"].([]byte),
+		MAC:    encrypted[DocumentMACField].([]byte),
+	}
+	if _, err := e.Decrypt(doc, taggedModel{}); err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+
+	doc.Age = doc.Note
+	if _, err := e.Decrypt(doc, taggedModel{}); err == nil {
+		t.Errorf("Decrypt() of document with added field should fail")
+	}
+}
+
+//Fields with options are sealed with subkeys of the derive version, they keep their ciphertexts when a keyring is rotated
+func Test_encryptionService_TagOptions_Rotation(t *testing.T) {
+	type optionModel struct {
+		Tenant string `bson:"tenant_id" encrypted:"false"`
+		Email  string `bson:"email" encrypted:"true,mode=deterministic"`
+		Note   string `bson:"note" encrypted:"true,aad=tenant_id"`
+	}
+	type optionModelEnc struct {
+		Tenant string `bson:"tenant_id" encrypted:"false"`
+		Email  []byte `bson:"email"`
+		Note   []byte `bson:"note"`
+	}
+
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	before, err := NewKeyringEncryptionService(Keyring{Current: 1, Keys: map[uint32][]byte{1: oldKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := NewKeyringEncryptionService(Keyring{Current: 2, Keys: map[uint32][]byte{1: oldKey, 2: newKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model := optionModel{Tenant: "acme", Email: "alice@example.com", Note: "note"}
+	old, err := before.EncryptToInterface(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := after.EncryptToInterface(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(old["email"].([]byte), rotated["email"].([]byte)) {
+		t.Errorf("deterministic field changed after rotation")
+	}
+
+	decrypted, err := after.Decrypt(optionModelEnc{Tenant: "acme", Email: old["email"].([]byte), Note: old["note"].([]byte)}, optionModel{})
+	if err != nil || !reflect.DeepEqual(decrypted, &model) {
+		t.Errorf("Decrypt() after rotation = %+v, %v", decrypted, err)
+	}
+
+	//The derive key itself does not open the field
+	aesGCM, err := newGCM(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := old["email"].([]byte)
+	if _, err := aesGCM.Open(nil, email[:aesGCM.NonceSize()], email[aesGCM.NonceSize():], nil); err == nil {
+		t.Errorf("field with options could be opened with the derive key")
+	}
+}
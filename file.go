@@ -0,0 +1,143 @@
+package encryption
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+//Option for the file encryption helpers
+type FileOption func(*fileOptions)
+
+type fileOptions struct {
+	overwrite bool
+}
+
+//Allow EncryptFile and DecryptFile to replace an existing destination file
+func WithOverwrite() FileOption {
+	return func(o *fileOptions) {
+		o.overwrite = true
+	}
+}
+
+//Encrypt file at src to dst using the streaming format, dst is written atomically and gets the file mode of src
+func (e *encryptionService) EncryptFile(src string, dst string, opts ...FileOption) error {
+	return e.transformFile(src, dst, opts, func(w io.Writer, r io.Reader) error {
+		ew, err := e.NewEncryptWriter(w)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(ew, r); err != nil {
+			return err
+		}
+
+		return ew.Close()
+	})
+}
+
+//Decrypt file at src created by EncryptFile to dst, nothing is written to dst when the file fails to authenticate
+func (e *encryptionService) DecryptFile(src string, dst string, opts ...FileOption) error {
+	return e.transformFile(src, dst, opts, func(w io.Writer, r io.Reader) error {
+		dr, err := e.NewDecryptReader(r)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(w, dr)
+
+		return err
+	})
+}
+
+//Write output of transform to a temp file next to dst and move it to dst once everything succeeded
+func (e *encryptionService) transformFile(src string, dst string, opts []FileOption, transform func(w io.Writer, r io.Reader) error) (err error) {
+	options := fileOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open source file, the following error occured: %s", err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat source file, the following error occured: %s", err)
+	}
+
+	if !options.overwrite {
+		if _, err := os.Lstat(dst); err == nil {
+			return fmt.Errorf("destination file %s already exists", dst)
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to stat destination file, the following error occured: %s", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file, the following error occured: %s", err)
+	}
+
+	//Remove partial output when anything fails
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = transform(tmp, in); err != nil {
+		return err
+	}
+
+	if err = tmp.Chmod(info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to set file mode, the following error occured: %s", err)
+	}
+
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temporary file, the following error occured: %s", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file, the following error occured: %s", err)
+	}
+
+	return publishFile(tmp.Name(), dst, options.overwrite)
+}
+
+//Move the finished temp file to dst, without overwrite a file created at dst in the meantime is never replaced
+func publishFile(tmp string, dst string, overwrite bool) error {
+	if overwrite {
+		if err := os.Rename(tmp, dst); err != nil {
+			return fmt.Errorf("failed to move temporary file to destination, the following error occured: %s", err)
+		}
+	} else {
+		//Unlike rename, link fails when dst exists
+		if err := os.Link(tmp, dst); err != nil {
+			if os.IsExist(err) {
+				return fmt.Errorf("destination file %s already exists", dst)
+			}
+			return fmt.Errorf("failed to link temporary file to destination, the following error occured: %s", err)
+		}
+		if err := os.Remove(tmp); err != nil {
+			return fmt.Errorf("failed to remove temporary file, the following error occured: %s", err)
+		}
+	}
+
+	//The new directory entry is only durable once the directory itself is synced
+	dir, err := os.Open(filepath.Dir(dst))
+	if err != nil {
+		return fmt.Errorf("failed to open destination directory, the following error occured: %s", err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync destination directory, the following error occured: %s", err)
+	}
+
+	return nil
+}
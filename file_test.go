@@ -0,0 +1,151 @@
+package encryption
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_encryptionService_EncryptFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   bool
+		opts       []FileOption
+		tamper     bool
+		wantEncErr bool
+		wantDecErr bool
+	}{
+		{
+			name: "successfully encrypt & decrypt file",
+		},
+		{
+			name:       "refuse to overwrite existing file",
+			existing:   true,
+			wantEncErr: true,
+		},
+		{
+			name:     "overwrite existing file when asked",
+			existing: true,
+			opts:     []FileOption{WithOverwrite()},
+		},
+		{
+			name:       "no output on authentication failure",
+			tamper:     true,
+			wantDecErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := encryptionService{
+				key: []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254},
+			}
+
+			dir := t.TempDir()
+			plainPath := filepath.Join(dir, "plain.txt")
+			encPath := filepath.Join(dir, "plain.txt.enc")
+			outPath := filepath.Join(dir, "out.txt")
+
+			plain := bytes.Repeat([]byte("file content "), 10000)
+			if err := os.WriteFile(plainPath, plain, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing {
+				if err := os.WriteFile(encPath, []byte("existing"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := e.EncryptFile(plainPath, encPath, tt.opts...)
+			if (err != nil) != tt.wantEncErr {
+				t.Errorf("encryptionService.EncryptFile() error = %v, wantErr %v", err, tt.wantEncErr)
+				return
+			}
+			if tt.wantEncErr {
+				return
+			}
+
+			info, err := os.Stat(encPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("encrypted file mode = %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+			}
+
+			if tt.tamper {
+				b, err := os.ReadFile(encPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b[len(b)-1] ^= 1
+				if err := os.WriteFile(encPath, b, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = e.DecryptFile(encPath, outPath)
+			if (err != nil) != tt.wantDecErr {
+				t.Errorf("encryptionService.DecryptFile() error = %v, wantErr %v", err, tt.wantDecErr)
+				return
+			}
+
+			if tt.wantDecErr {
+				entries, err := os.ReadDir(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(entries) != 2 {
+					t.Errorf("expected partial output to be removed, found %d files", len(entries))
+				}
+				return
+			}
+
+			got, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("decrypted file does not match input")
+			}
+		})
+	}
+}
+
+//A destination created after the existence check must not be replaced
+func Test_publishFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		overwrite bool
+		wantErr   bool
+		want      string
+	}{
+		{name: "destination created in the meantime", overwrite: false, wantErr: true, want: "existing"},
+		{name: "overwrite", overwrite: true, wantErr: false, want: "new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tmp, dst := filepath.Join(dir, ".out.tmp"), filepath.Join(dir, "out")
+			if err := os.WriteFile(tmp, []byte("new"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(dst, []byte("existing"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := publishFile(tmp, dst, tt.overwrite)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("publishFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got, err := os.ReadFile(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("destination = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
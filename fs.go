@@ -0,0 +1,416 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+//Define file name encryption constants
+const (
+	nameKeyLabel = "globe-protocol/encryption file name key"
+	nameIVLabel  = "globe-protocol/encryption file name iv"
+)
+
+//Get the cipher used to encrypt file names and the key used to derive their nonces
+func (e *encryptionService) fileNameKeys() (cipher.AEAD, []byte, error) {
+	nameKey, err := e.deriveKey(nameKeyLabel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ivKey, err := e.deriveKey(nameIVLabel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aesGCM, err := newGCM(nameKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return aesGCM, ivKey, nil
+}
+
+//Encrypt a file name deterministically so that the encrypted file can be found by its original name
+func (e *encryptionService) EncryptFileName(name string) (string, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return "", fmt.Errorf("%q is not a valid file name", name)
+	}
+
+	aesGCM, ivKey, err := e.fileNameKeys()
+	if err != nil {
+		return "", err
+	}
+
+	//Nonce is derived from the name itself, equal names result in equal encrypted names
+	mac := hmac.New(sha256.New, ivKey)
+	mac.Write([]byte(name))
+	nonce := mac.Sum(nil)[:aesGCM.NonceSize()]
+
+	return base64.RawURLEncoding.EncodeToString(aesGCM.Seal(nonce, nonce, []byte(name), nil)), nil
+}
+
+//Decrypt a file name encrypted by EncryptFileName
+func (e *encryptionService) DecryptFileName(name string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil {
+		return "", fmt.Errorf("%q is not an encrypted file name", name)
+	}
+
+	aesGCM, ivKey, err := e.fileNameKeys()
+	if err != nil {
+		return "", err
+	}
+
+	if len(b) < aesGCM.NonceSize()+aesGCM.Overhead() {
+		return "", fmt.Errorf("%q is not an encrypted file name", name)
+	}
+
+	plain, err := gcmSealer{aesGCM: aesGCM}.open(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt file name, the following error occured: %s", err)
+	}
+
+	//Make sure the nonce was derived from the name
+	mac := hmac.New(sha256.New, ivKey)
+	mac.Write(plain)
+	if !hmac.Equal(mac.Sum(nil)[:aesGCM.NonceSize()], b[:aesGCM.NonceSize()]) {
+		return "", errors.New("failed to decrypt file name, nonce does not match name")
+	}
+
+	return string(plain), nil
+}
+
+type decryptFS struct {
+	fsys  fs.FS
+	e     *encryptionService
+	names bool
+}
+
+//Wrap a file system containing files created by EncryptFile so that reading from it returns the decrypted files
+func (e *encryptionService) NewDecryptFS(fsys fs.FS, encryptedNames bool) fs.FS {
+	return &decryptFS{
+		fsys:  fsys,
+		e:     e,
+		names: encryptedNames,
+	}
+}
+
+//Get path of the encrypted file in the underlying file system
+func (d *decryptFS) encryptedPath(name string) (string, error) {
+	if !d.names || name == "." {
+		return name, nil
+	}
+
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		encrypted, err := d.e.EncryptFileName(part)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = encrypted
+	}
+
+	return strings.Join(parts, "/"), nil
+}
+
+//Get plain name of an entry in the underlying file system
+func (d *decryptFS) plainName(name string) (string, error) {
+	if !d.names {
+		return name, nil
+	}
+
+	return d.e.DecryptFileName(name)
+}
+
+func (d *decryptFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	encrypted, err := d.encryptedPath(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	f, err := d.fsys.Open(encrypted)
+	if err != nil {
+		return nil, underlyingPathError("open", name, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if info.IsDir() {
+		return &decryptDir{File: f, fs: d, name: path.Base(name)}, nil
+	}
+
+	r, err := d.e.NewDecryptReader(f)
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &decryptFile{
+		f:    f,
+		r:    r.(*decryptReader),
+		info: plainFileInfo{FileInfo: info, name: path.Base(name)},
+	}, nil
+}
+
+func (d *decryptFS) ReadFile(name string) ([]byte, error) {
+	f, err := d.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+
+	return b, nil
+}
+
+func (d *decryptFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	encrypted, err := d.encryptedPath(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	info, err := fs.Stat(d.fsys, encrypted)
+	if err != nil {
+		return nil, underlyingPathError("stat", name, err)
+	}
+
+	return plainFileInfo{FileInfo: info, name: path.Base(name)}, nil
+}
+
+func (d *decryptFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	encrypted, err := d.encryptedPath(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	entries, err := fs.ReadDir(d.fsys, encrypted)
+	if err != nil {
+		return nil, underlyingPathError("readdir", name, err)
+	}
+
+	//Decrypted names are no longer in the order of the encrypted names
+	plain := d.plainEntries(entries)
+	sort.Slice(plain, func(i, j int) bool {
+		return plain[i].Name() < plain[j].Name()
+	})
+
+	return plain, nil
+}
+
+//Convert entries of the underlying file system, entries with names that cannot be decrypted are skipped
+func (d *decryptFS) plainEntries(entries []fs.DirEntry) []fs.DirEntry {
+	plain := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		name, err := d.plainName(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		plain = append(plain, plainDirEntry{DirEntry: entry, name: name})
+	}
+
+	return plain
+}
+
+//Report errors of the underlying file system with the plain path instead of the encrypted path
+func underlyingPathError(op string, name string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+//Get the size of the plaintext of an encrypted stream of the given size
+func plainSize(size int64) int64 {
+	const overhead = 16
+	payload := size - int64(streamHeaderSize)
+	if payload < overhead {
+		return 0
+	}
+
+	chunks := payload/(StreamChunkSize+overhead) + 1
+
+	return payload - chunks*overhead
+}
+
+type plainFileInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i plainFileInfo) Name() string {
+	return i.name
+}
+
+func (i plainFileInfo) Size() int64 {
+	if i.FileInfo.IsDir() {
+		return i.FileInfo.Size()
+	}
+
+	return plainSize(i.FileInfo.Size())
+}
+
+type plainDirEntry struct {
+	fs.DirEntry
+	name string
+}
+
+func (d plainDirEntry) Name() string {
+	return d.name
+}
+
+func (d plainDirEntry) Info() (fs.FileInfo, error) {
+	info, err := d.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	return plainFileInfo{FileInfo: info, name: d.name}, nil
+}
+
+type decryptDir struct {
+	fs.File
+	fs   *decryptFS
+	name string
+}
+
+func (d *decryptDir) Stat() (fs.FileInfo, error) {
+	info, err := d.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return plainFileInfo{FileInfo: info, name: d.name}, nil
+}
+
+func (d *decryptDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *decryptDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	dir, ok := d.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: errors.New("not implemented")}
+	}
+
+	//Keep reading when all entries of a batch were skipped so that an empty batch is only returned at the end
+	for {
+		entries, err := dir.ReadDir(n)
+		plain := d.fs.plainEntries(entries)
+		if len(plain) > 0 || len(entries) == 0 || err != nil || n <= 0 {
+			return plain, err
+		}
+	}
+}
+
+type decryptFile struct {
+	f    fs.File
+	r    *decryptReader
+	info plainFileInfo
+	pos  int64
+}
+
+func (f *decryptFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *decryptFile) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	f.pos += int64(n)
+
+	return n, err
+}
+
+func (f *decryptFile) Close() error {
+	return f.f.Close()
+}
+
+//Seek to any position in the plaintext, only the chunk containing the position is decrypted
+func (f *decryptFile) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := f.f.(io.Seeker)
+	if !ok {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: errors.New("underlying file does not support seeking")}
+	}
+
+	size := f.info.Size()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+
+	chunk := offset / StreamChunkSize
+	if offset >= size {
+		chunk = size / StreamChunkSize
+	}
+
+	if _, err := seeker.Seek(int64(streamHeaderSize)+chunk*int64(StreamChunkSize+f.r.aesGCM.Overhead()), io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	f.r.counter = uint64(chunk)
+	f.r.buf = nil
+	f.r.done = false
+	f.r.err = nil
+	f.pos = offset
+
+	if offset >= size {
+		f.r.done = true
+		return offset, nil
+	}
+
+	//Skip the part of the chunk before the offset
+	if _, err := io.CopyN(io.Discard, f.r, offset-chunk*StreamChunkSize); err != nil {
+		return 0, err
+	}
+
+	return offset, nil
+}
+
+//Check that the interfaces used by http.FileServer and template.ParseFS are implemented
+var (
+	_ fs.ReadFileFS  = (*decryptFS)(nil)
+	_ fs.ReadDirFS   = (*decryptFS)(nil)
+	_ fs.StatFS      = (*decryptFS)(nil)
+	_ io.ReadSeeker  = (*decryptFile)(nil)
+	_ fs.ReadDirFile = (*decryptDir)(nil)
+)
@@ -0,0 +1,139 @@
+package encryption
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func Test_encryptionService_NewDecryptFS(t *testing.T) {
+	files := map[string][]byte{
+		"config.yaml":     []byte("name: globe\n"),
+		"assets/big.bin":  bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7}, StreamChunkSize/3),
+		"assets/empty.js": {},
+	}
+
+	tests := []struct {
+		name           string
+		encryptedNames bool
+	}{
+		{
+			name:           "plain file names",
+			encryptedNames: false,
+		},
+		{
+			name:           "encrypted file names",
+			encryptedNames: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &encryptionService{
+				key: []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254},
+			}
+
+			src, dst := t.TempDir(), t.TempDir()
+			for name, content := range files {
+				plainPath := filepath.Join(src, filepath.Base(name))
+				if err := os.WriteFile(plainPath, content, 0600); err != nil {
+					t.Fatal(err)
+				}
+
+				dir, base := filepath.Split(name)
+				if tt.encryptedNames {
+					if dir != "" {
+						encDir, err := e.EncryptFileName(filepath.Clean(dir))
+						if err != nil {
+							t.Fatal(err)
+						}
+						dir = encDir
+					}
+
+					encBase, err := e.EncryptFileName(base)
+					if err != nil {
+						t.Fatal(err)
+					}
+					base = encBase
+				}
+
+				if err := os.MkdirAll(filepath.Join(dst, dir), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := e.EncryptFile(plainPath, filepath.Join(dst, dir, base)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fsys := e.NewDecryptFS(os.DirFS(dst), tt.encryptedNames)
+			if err := fstest.TestFS(fsys, "config.yaml", "assets/big.bin", "assets/empty.js"); err != nil {
+				t.Errorf("fstest.TestFS() error = %v", err)
+			}
+
+			for name, content := range files {
+				got, err := fs.ReadFile(fsys, name)
+				if err != nil {
+					t.Errorf("fs.ReadFile(%s) error = %v", name, err)
+					continue
+				}
+				if !bytes.Equal(got, content) {
+					t.Errorf("fs.ReadFile(%s) does not match input", name)
+				}
+			}
+
+			f, err := fsys.Open("assets/big.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			offset := int64(StreamChunkSize + 10)
+			if _, err := f.(io.Seeker).Seek(offset, io.SeekStart); err != nil {
+				t.Fatalf("Seek() error = %v", err)
+			}
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, files["assets/big.bin"][offset:]) {
+				t.Errorf("content after Seek() does not match input")
+			}
+		})
+	}
+}
+
+func Test_encryptionService_FileName(t *testing.T) {
+	e := &encryptionService{
+		key: []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254},
+	}
+
+	first, err := e.EncryptFileName("report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := e.EncryptFileName("report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Errorf("EncryptFileName() is not deterministic, got %s and %s", first, second)
+	}
+
+	got, err := e.DecryptFileName(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "report.csv" {
+		t.Errorf("DecryptFileName() = %s, want report.csv", got)
+	}
+
+	if _, err := e.EncryptFileName("dir/report.csv"); err == nil {
+		t.Errorf("EncryptFileName() expected error for name containing a slash")
+	}
+	if _, err := e.DecryptFileName("report.csv"); err == nil {
+		t.Errorf("DecryptFileName() expected error for plain name")
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/globe-protocol/encryption
 
-go 1.17
+go 1.20
 
 require github.com/golang/mock v1.6.0
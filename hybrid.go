@@ -0,0 +1,421 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//Define envelope format constants
+const (
+	EnvelopeMagic       = "GLBH"
+	EnvelopeVersion     = 1
+	stanzaX25519   byte = 1
+	stanzaP256     byte = 2
+	stanzaRSA      byte = 3
+	keyIDSize           = 8
+	contentKeySize      = 32
+	minRSABits          = 2048
+	hybridLabel         = "globe-protocol/encryption hybrid"
+)
+
+//Public side of a key the content key of an envelope is wrapped for
+type recipient interface {
+	stanzaType() byte
+	keyID() []byte
+	wrap(cek []byte) ([]byte, error)
+}
+
+//Private side of a key that can unwrap the content key of an envelope
+type identity interface {
+	recipient
+	unwrap(body []byte) ([]byte, error)
+}
+
+//Wrapped content key for a single recipient
+type stanza struct {
+	typ   byte
+	keyID []byte
+	body  []byte
+}
+
+//Parsed envelope, the body is encrypted once with the content key which is wrapped per recipient
+type envelope struct {
+	stanzas []stanza
+	body    []byte
+}
+
+//Create an identifier for a public key so that the matching stanza can be found without trying every stanza
+func newKeyID(typ byte, publicKey []byte) []byte {
+	sum := sha256.Sum256(append([]byte{typ}, publicKey...))
+
+	return sum[:keyIDSize]
+}
+
+//HKDF-SHA256 (RFC 5869) to derive wrapping keys from shared secrets
+func hkdfSHA256(secret, salt, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	out := []byte{}
+	prev := []byte{}
+	for i := byte(1); len(out) < length; i++ {
+		expand := hmac.New(sha256.New, prk)
+		expand.Write(prev)
+		expand.Write(info)
+		expand.Write([]byte{i})
+		prev = expand.Sum(nil)
+		out = append(out, prev...)
+	}
+
+	return out[:length]
+}
+
+//Seal data with a single use key, a zero nonce is safe since the key is never reused
+func sealOnce(key, plain []byte) ([]byte, error) {
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return aesGCM.Seal(nil, make([]byte, aesGCM.NonceSize()), plain, nil), nil
+}
+
+func openOnce(key, sealed []byte) ([]byte, error) {
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return aesGCM.Open(nil, make([]byte, aesGCM.NonceSize()), sealed, nil)
+}
+
+type ecdhRecipient struct {
+	pub *ecdh.PublicKey
+}
+
+func (r *ecdhRecipient) stanzaType() byte {
+	if r.pub.Curve() == ecdh.X25519() {
+		return stanzaX25519
+	}
+
+	return stanzaP256
+}
+
+func (r *ecdhRecipient) keyID() []byte {
+	return newKeyID(r.stanzaType(), r.pub.Bytes())
+}
+
+//Wrap content key with a key derived from an ephemeral key exchange, the ephemeral public key is stored in the stanza
+func (r *ecdhRecipient) wrap(cek []byte) ([]byte, error) {
+	ephemeral, err := r.pub.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := ephemeral.ECDH(r.pub)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeralPub := ephemeral.PublicKey().Bytes()
+	wrapped, err := sealOnce(ecdhWrapKey(shared, ephemeralPub, r.pub.Bytes()), cek)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ephemeralPub, wrapped...), nil
+}
+
+func ecdhWrapKey(shared, ephemeralPub, recipientPub []byte) []byte {
+	salt := append(append([]byte{}, ephemeralPub...), recipientPub...)
+
+	return hkdfSHA256(shared, salt, []byte(hybridLabel), contentKeySize)
+}
+
+type ecdhIdentity struct {
+	ecdhRecipient
+	priv *ecdh.PrivateKey
+}
+
+func (i *ecdhIdentity) unwrap(body []byte) ([]byte, error) {
+	size := len(i.pub.Bytes())
+	if len(body) < size {
+		return nil, errors.New("stanza is too short")
+	}
+
+	ephemeral, err := i.pub.Curve().NewPublicKey(body[:size])
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := i.priv.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	return openOnce(ecdhWrapKey(shared, body[:size], i.pub.Bytes()), body[size:])
+}
+
+type rsaRecipient struct {
+	pub *rsa.PublicKey
+}
+
+func (r *rsaRecipient) stanzaType() byte {
+	return stanzaRSA
+}
+
+func (r *rsaRecipient) keyID() []byte {
+	return newKeyID(stanzaRSA, x509.MarshalPKCS1PublicKey(r.pub))
+}
+
+func (r *rsaRecipient) wrap(cek []byte) ([]byte, error) {
+	return rsa.EncryptOAEP(sha256.New(), rand.Reader, r.pub, cek, []byte(hybridLabel))
+}
+
+type rsaIdentity struct {
+	rsaRecipient
+	priv *rsa.PrivateKey
+}
+
+func (i *rsaIdentity) unwrap(body []byte) ([]byte, error) {
+	return rsa.DecryptOAEP(sha256.New(), rand.Reader, i.priv, body, []byte(hybridLabel))
+}
+
+//Convert a supported public key to a recipient
+func newRecipient(key crypto.PublicKey) (recipient, error) {
+	switch k := key.(type) {
+	case *ecdh.PublicKey:
+		if k.Curve() != ecdh.X25519() && k.Curve() != ecdh.P256() {
+			return nil, errors.New("only X25519 and P-256 ecdh keys are supported")
+		}
+		return &ecdhRecipient{pub: k}, nil
+	case *ecdsa.PublicKey:
+		pub, err := k.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		return newRecipient(pub)
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("rsa keys should be at least %d bits", minRSABits)
+		}
+		return &rsaRecipient{pub: k}, nil
+	default:
+		return nil, fmt.Errorf("%T is not a supported public key type", key)
+	}
+}
+
+//Convert a supported private key to an identity
+func newIdentity(key crypto.PrivateKey) (identity, error) {
+	switch k := key.(type) {
+	case *ecdh.PrivateKey:
+		r, err := newRecipient(k.PublicKey())
+		if err != nil {
+			return nil, err
+		}
+		return &ecdhIdentity{ecdhRecipient: *r.(*ecdhRecipient), priv: k}, nil
+	case *ecdsa.PrivateKey:
+		priv, err := k.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		return newIdentity(priv)
+	case *rsa.PrivateKey:
+		r, err := newRecipient(&k.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		return &rsaIdentity{rsaRecipient: *r.(*rsaRecipient), priv: k}, nil
+	default:
+		return nil, fmt.Errorf("%T is not a supported private key type", key)
+	}
+}
+
+//Create encryption service that encrypts to the given public keys, the service itself cannot decrypt
+func NewPublicKeyEncryptionService(recipients ...crypto.PublicKey) (EncryptionService, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("at least one recipient public key is required")
+	}
+
+	e := &encryptionService{}
+	for _, key := range recipients {
+		r, err := newRecipient(key)
+		if err != nil {
+			return nil, err
+		}
+		e.recipients = append(e.recipients, r)
+	}
+
+	return e, nil
+}
+
+//Create encryption service that decrypts using the private key and encrypts to its public key and the given other recipients
+func NewPrivateKeyEncryptionService(privateKey crypto.PrivateKey, recipients ...crypto.PublicKey) (EncryptionService, error) {
+	id, err := newIdentity(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &encryptionService{
+		identity:   id,
+		recipients: []recipient{id},
+	}
+	for _, key := range recipients {
+		r, err := newRecipient(key)
+		if err != nil {
+			return nil, err
+		}
+		e.recipients = append(e.recipients, r)
+	}
+
+	return e, nil
+}
+
+//Encrypt plaintext once with a random content key and wrap that key for every recipient
+func sealEnvelope(recipients []recipient, plain []byte) ([]byte, error) {
+	cek := make([]byte, contentKeySize)
+	if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+		return nil, err
+	}
+
+	env := &envelope{}
+	for _, r := range recipients {
+		body, err := r.wrap(cek)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wrap content key, the following error occured: %s", err)
+		}
+		env.stanzas = append(env.stanzas, stanza{typ: r.stanzaType(), keyID: r.keyID(), body: body})
+	}
+
+	aesGCM, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	env.body = aesGCM.Seal(nonce, nonce, plain, []byte(EnvelopeMagic))
+
+	return env.marshal()
+}
+
+//Find the stanza of the private key of the service, unwrap the content key and decrypt the body
+func (e encryptionService) openEnvelope(b []byte) ([]byte, error) {
+	if e.identity == nil {
+		return nil, errors.New("service was created without a private key and cannot decrypt")
+	}
+
+	env, err := parseEnvelope(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return env.open(e.identity)
+}
+
+//Decrypt the body using the content key wrapped for the identity
+func (env *envelope) open(id identity) ([]byte, error) {
+	cek, err := env.contentKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return env.openWithKey(cek)
+}
+
+func (env *envelope) openWithKey(cek []byte) ([]byte, error) {
+	aesGCM, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(env.body) < aesGCM.NonceSize() {
+		return nil, errors.New("envelope body is too short")
+	}
+
+	return aesGCM.Open(nil, env.body[:aesGCM.NonceSize()], env.body[aesGCM.NonceSize():], []byte(EnvelopeMagic))
+}
+
+//Unwrap the content key using the stanza belonging to the identity
+func (env *envelope) contentKey(id identity) ([]byte, error) {
+	i := env.find(id)
+	if i == -1 {
+		return nil, errors.New("ciphertext is not encrypted for this key")
+	}
+
+	cek, err := id.unwrap(env.stanzas[i].body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap content key, the following error occured: %s", err)
+	}
+
+	return cek, nil
+}
+
+//Envelope layout: magic | version | stanza count | stanzas (type | key id | body length | body) | nonce | ciphertext
+func (env *envelope) marshal() ([]byte, error) {
+	if len(env.stanzas) > 0xffff {
+		return nil, errors.New("envelope has too many recipients")
+	}
+
+	out := append([]byte(EnvelopeMagic), EnvelopeVersion)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(env.stanzas)))
+	for _, s := range env.stanzas {
+		if len(s.body) > 0xffff {
+			return nil, errors.New("stanza body is too long")
+		}
+
+		out = append(out, s.typ)
+		out = append(out, s.keyID...)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(s.body)))
+		out = append(out, s.body...)
+	}
+
+	return append(out, env.body...), nil
+}
+
+func parseEnvelope(b []byte) (*envelope, error) {
+	headerSize := len(EnvelopeMagic) + 3
+	if len(b) < headerSize || !bytes.Equal(b[:len(EnvelopeMagic)], []byte(EnvelopeMagic)) {
+		return nil, errors.New("input is not an encrypted envelope")
+	}
+	if b[len(EnvelopeMagic)] != EnvelopeVersion {
+		return nil, fmt.Errorf("envelope version %d is not supported", b[len(EnvelopeMagic)])
+	}
+
+	count := int(binary.BigEndian.Uint16(b[len(EnvelopeMagic)+1:]))
+	b = b[headerSize:]
+
+	env := &envelope{}
+	for i := 0; i < count; i++ {
+		if len(b) < 1+keyIDSize+2 {
+			return nil, errors.New("envelope is truncated")
+		}
+
+		s := stanza{typ: b[0], keyID: b[1 : 1+keyIDSize]}
+		size := int(binary.BigEndian.Uint16(b[1+keyIDSize:]))
+		b = b[1+keyIDSize+2:]
+		if len(b) < size {
+			return nil, errors.New("envelope is truncated")
+		}
+
+		s.body, b = b[:size], b[size:]
+		env.stanzas = append(env.stanzas, s)
+	}
+	env.body = b
+
+	return env, nil
+}
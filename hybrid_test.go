@@ -0,0 +1,160 @@
+package encryption
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"reflect"
+	"testing"
+)
+
+func Test_NewPublicKeyEncryptionService(t *testing.T) {
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256Key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		privateKey crypto.PrivateKey
+		publicKeys []crypto.PublicKey
+		wantErr    bool
+	}{
+		{
+			name:       "X25519",
+			privateKey: x25519Key,
+			publicKeys: []crypto.PublicKey{x25519Key.PublicKey()},
+			wantErr:    false,
+		},
+		{
+			name:       "P-256",
+			privateKey: p256Key,
+			publicKeys: []crypto.PublicKey{p256Key.PublicKey()},
+			wantErr:    false,
+		},
+		{
+			name:       "RSA-OAEP",
+			privateKey: rsaKey,
+			publicKeys: []crypto.PublicKey{&rsaKey.PublicKey},
+			wantErr:    false,
+		},
+		{
+			name:       "multiple recipients",
+			privateKey: rsaKey,
+			publicKeys: []crypto.PublicKey{x25519Key.PublicKey(), &rsaKey.PublicKey, p256Key.PublicKey()},
+			wantErr:    false,
+		},
+		{
+			name:       "not encrypted for private key",
+			privateKey: otherKey,
+			publicKeys: []crypto.PublicKey{x25519Key.PublicKey()},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer, err := NewPublicKeyEncryptionService(tt.publicKeys...)
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while creating public key service", err)
+			}
+			reader, err := NewPrivateKeyEncryptionService(tt.privateKey)
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while creating private key service", err)
+			}
+
+			encrypted, err := writer.EncryptStr("test inputf5t67yyyyu857tfo")
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while encrypting", err)
+			}
+
+			if _, err := writer.DecryptStr(encrypted); err == nil {
+				t.Errorf("expected public key service to be unable to decrypt")
+			}
+
+			got, err := reader.DecryptStr(encrypted)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.DecryptStr() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && got != "test inputf5t67yyyyu857tfo" {
+				t.Errorf("encryptionService.DecryptStr() = %v, want %v", got, "test inputf5t67yyyyu857tfo")
+			}
+		})
+	}
+}
+
+func Test_NewPrivateKeyEncryptionService_Struct(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer, err := NewPublicKeyEncryptionService(key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := NewPrivateKeyEncryptionService(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := stringfloatbool{
+		String:    "123Test",
+		Float64:   64.64,
+		Bool:      true,
+		StringArr: []string{"test value", "test, value 2"},
+	}
+
+	encryptedData, err := writer.EncryptToInterface(data)
+	if err != nil {
+		t.Fatalf("Did not expect error: %s while encrypting object", err)
+	}
+
+	encryptedObj := stringfloatboolEnc{
+		String:    encryptedData["String"].(string),
+		Float64:   encryptedData["Float64"].([]byte),
+		Bool:      encryptedData["Bool"].([]byte),
+		StringArr: encryptedData["StringArr"].([]byte),
+		EmptyVal:  encryptedData["EmptyVal"].([]byte),
+	}
+
+	decryptedData, err := reader.Decrypt(encryptedObj, stringfloatbool{})
+	if err != nil {
+		t.Fatalf("Did not expect error: %s while decrypting object", err)
+	}
+
+	if !reflect.DeepEqual(decryptedData, &data) {
+		t.Errorf("decrypted body = %+v\n want = %+v\n", decryptedData, &data)
+	}
+}
+
+func Test_NewPublicKeyEncryptionService_Errors(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewPublicKeyEncryptionService(); err == nil {
+		t.Errorf("expected error without recipients")
+	}
+	if _, err := NewPublicKeyEncryptionService(&small.PublicKey); err == nil {
+		t.Errorf("expected error for small rsa key")
+	}
+	if _, err := NewPublicKeyEncryptionService("not a key"); err == nil {
+		t.Errorf("expected error for unsupported key type")
+	}
+}
@@ -0,0 +1,392 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+//Define ciphertext formats reported by Inspect
+const (
+	FormatBinary   = "binary"
+	FormatText     = "text"
+	FormatEnvelope = "envelope"
+	FormatStream   = "stream"
+	FormatToken    = "token"
+	FormatFernet   = "fernet"
+	FormatOpenSSL  = "openssl"
+	FormatJWE      = "jwe"
+	FormatUnknown  = "unknown"
+	gcmNonceSize   = 12
+	gcmTagSize     = 16
+)
+
+//Names of the stanza types of envelopes
+var stanzaTypeNames = map[byte]string{
+	stanzaX25519:    "X25519",
+	stanzaP256:      "P-256",
+	stanzaRSA:       "RSA-OAEP",
+	stanzaSymmetric: "symmetric",
+}
+
+//Recipient of an envelope
+type EnvelopeRecipient struct {
+	Type  string
+	KeyID string
+}
+
+//Information about a ciphertext that can be read without decrypting it
+type CiphertextInfo struct {
+	Format string
+	//Encoding the input was given in, raw, base64, base64url or text
+	Encoding   string
+	Version    int
+	Algorithm  string
+	KeyVersion uint32
+	KeyID      string
+	Nonce      []byte
+	Salt       []byte
+	//Length of the encrypted payload without headers, nonce and tag, CBC formats include the padding
+	PayloadLength int
+	Recipients    []EnvelopeRecipient
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+	//Problems found in the input, like truncation or invalid base64
+	Problems []string
+}
+
+//Result of Diagnose
+type Diagnosis struct {
+	CiphertextInfo
+	//Name of the key that authenticates the ciphertext, empty when none of the keys does
+	MatchedKey string
+}
+
+//Inspect ciphertext of any format produced by this package without decrypting it
+func Inspect(ciphertext []byte) *CiphertextInfo {
+	info, _ := inspect(ciphertext)
+
+	return info
+}
+
+//Inspect ciphertext and try the given keys to find the one that authenticates it
+func Diagnose(ciphertext []byte, keys map[string][]byte) *Diagnosis {
+	info, raw := inspect(ciphertext)
+	d := &Diagnosis{CiphertextInfo: *info}
+
+	if info.Format == FormatUnknown || info.Format == FormatOpenSSL {
+		d.Problems = append(d.Problems, fmt.Sprintf("%s input cannot be checked with keys", info.Format))
+		return d
+	}
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if authenticates(info.Format, ciphertext, raw, keys[name]) {
+			d.MatchedKey = name
+			return d
+		}
+	}
+
+	d.Problems = append(d.Problems, "none of the keys authenticates the ciphertext, it was produced with another key or has been modified")
+
+	return d
+}
+
+//Check whether key authenticates the ciphertext, raw is the decoded ciphertext returned by inspect
+func authenticates(format string, ciphertext []byte, raw []byte, key []byte) bool {
+	e := &encryptionService{key: key}
+	var err error
+
+	switch format {
+	case FormatBinary, FormatText, FormatToken:
+		aesGCM, gcmErr := newGCM(key)
+		if gcmErr != nil {
+			return false
+		}
+		if format == FormatToken {
+			if len(raw) < tokenHeadSize {
+				return false
+			}
+			raw = raw[tokenHeadSize:]
+		}
+		_, err = gcmSealer{aesGCM: aesGCM}.open(raw)
+	case FormatEnvelope:
+		_, err = e.DecryptEnvelope(raw)
+	case FormatStream:
+		var r io.Reader
+		if r, err = e.NewDecryptReader(bytes.NewReader(raw)); err == nil {
+			_, err = r.Read(make([]byte, 1))
+			if err == io.EOF {
+				err = nil
+			}
+		}
+	case FormatFernet:
+		if len(key) != fernetKeySize {
+			return false
+		}
+		_, _, _, err = (&Fernet{keys: [][]byte{key}}).verify(string(bytes.TrimSpace(ciphertext)))
+	case FormatJWE:
+		_, err = e.DecryptJWE(string(bytes.TrimSpace(ciphertext)))
+	default:
+		return false
+	}
+
+	return err == nil
+}
+
+//Detect format of the input and get the decoded ciphertext
+func inspect(ciphertext []byte) (*CiphertextInfo, []byte) {
+	info := &CiphertextInfo{Format: FormatUnknown, Encoding: "raw"}
+	trimmed := bytes.TrimSpace(ciphertext)
+
+	if len(trimmed) == 0 {
+		info.Problems = append(info.Problems, "input is empty")
+		return info, nil
+	}
+
+	if bytes.HasPrefix(trimmed, []byte(TextPrefix)) {
+		return inspectText(info, trimmed)
+	}
+	if inspectJWE(info, trimmed) {
+		return info, nil
+	}
+
+	raw := ciphertext
+	if !hasKnownMagic(ciphertext) && isBase64Text(trimmed) {
+		decoded, encoding, err := decodeAnyBase64(string(trimmed))
+		if err != nil {
+			info.Problems = append(info.Problems, "input looks like base64 but cannot be decoded, it may be truncated or mangled")
+			return info, nil
+		}
+		raw, info.Encoding = decoded, encoding
+	}
+
+	inspectRaw(info, raw)
+
+	//Input without any of +/-_ decodes under both alphabets, formats that are always written as base64url are reported as such
+	if info.Encoding == "base64" && !bytes.ContainsAny(trimmed, "+/") && (info.Format == FormatFernet || info.Format == FormatToken) {
+		info.Encoding = "base64url"
+	}
+
+	return info, raw
+}
+
+func inspectText(info *CiphertextInfo, text []byte) (*CiphertextInfo, []byte) {
+	info.Format, info.Encoding, info.Algorithm = FormatText, "text", "AES-GCM"
+
+	version, raw, err := parseTextCiphertext(text)
+	if err != nil {
+		info.Problems = append(info.Problems, err.Error())
+		return info, nil
+	}
+
+	info.KeyVersion = version
+	info.KeyID = fmt.Sprintf("v%d", version)
+	inspectGCM(info, raw, 0)
+
+	return info, raw
+}
+
+func inspectJWE(info *CiphertextInfo, b []byte) bool {
+	parts := strings.Split(string(b), ".")
+	if len(parts) != 5 {
+		return false
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+
+	var header jweHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg == "" {
+		return false
+	}
+
+	info.Format, info.Encoding = FormatJWE, "base64url"
+	info.Algorithm = header.Alg + " " + header.Enc
+	info.KeyID = header.Kid
+
+	iv, ivErr := base64.RawURLEncoding.DecodeString(parts[2])
+	ciphertext, ctErr := base64.RawURLEncoding.DecodeString(parts[3])
+	if ivErr != nil || ctErr != nil {
+		info.Problems = append(info.Problems, "JWE parts are not valid base64url")
+		return true
+	}
+
+	info.Nonce = iv
+	info.PayloadLength = len(ciphertext)
+
+	return true
+}
+
+//Parse decoded input, formats with a header are recognized by their magic bytes
+func inspectRaw(info *CiphertextInfo, raw []byte) {
+	switch {
+	case bytes.HasPrefix(raw, []byte(EnvelopeMagic)):
+		info.Format, info.Algorithm = FormatEnvelope, "AES-256-GCM"
+		info.Version = int(byteAt(raw, len(EnvelopeMagic)))
+
+		env, err := parseEnvelope(raw)
+		if err != nil {
+			info.Problems = append(info.Problems, err.Error())
+			return
+		}
+
+		for _, s := range env.stanzas {
+			name, ok := stanzaTypeNames[s.typ]
+			if !ok {
+				name = fmt.Sprintf("unknown (%d)", s.typ)
+			}
+			info.Recipients = append(info.Recipients, EnvelopeRecipient{Type: name, KeyID: hex.EncodeToString(s.keyID)})
+		}
+		inspectGCM(info, env.body, 0)
+	case bytes.HasPrefix(raw, []byte(StreamMagic)):
+		info.Format, info.Algorithm = FormatStream, "AES-256-GCM"
+		info.Version = int(byteAt(raw, len(StreamMagic)))
+
+		if len(raw) < streamHeaderSize+gcmTagSize {
+			info.Problems = append(info.Problems, "stream is truncated")
+			return
+		}
+		info.Salt = raw[len(StreamMagic)+1 : streamHeaderSize]
+		info.PayloadLength = int(plainSize(int64(len(raw))))
+	case bytes.HasPrefix(raw, []byte(TokenMagic)):
+		info.Format, info.Algorithm = FormatToken, "AES-GCM"
+		info.Version = int(byteAt(raw, len(TokenMagic)))
+
+		issuedAt, expiresAt, err := parseTokenHeader(raw)
+		if err != nil {
+			info.Problems = append(info.Problems, err.Error())
+			return
+		}
+		info.IssuedAt, info.ExpiresAt = issuedAt, expiresAt
+
+		//The header is sealed together with the payload
+		inspectGCM(info, raw[tokenHeadSize:], tokenHeadSize)
+	case bytes.HasPrefix(raw, []byte(OpenSSLMagic)):
+		info.Format, info.Algorithm = FormatOpenSSL, "AES-256-CBC"
+		if len(raw) < openSSLHeaderSize {
+			info.Problems = append(info.Problems, "OpenSSL header is truncated")
+			return
+		}
+
+		info.Salt = raw[len(OpenSSLMagic):openSSLHeaderSize]
+		info.PayloadLength = len(raw) - openSSLHeaderSize
+		if info.PayloadLength == 0 || info.PayloadLength%aes.BlockSize != 0 {
+			info.Problems = append(info.Problems, "OpenSSL ciphertext is not a multiple of the block size, it may be truncated")
+		}
+	case info.Encoding != "raw" && isFernetHeader(raw):
+		info.Format, info.Algorithm = FormatFernet, "AES-128-CBC HMAC-SHA256"
+		info.Version = int(FernetVersion)
+		info.IssuedAt = time.Unix(int64(binary.BigEndian.Uint64(raw[1:9])), 0)
+
+		if len(raw) < fernetMinLen {
+			info.Problems = append(info.Problems, fmt.Sprintf("fernet token of %d bytes is shorter than header, one block and HMAC, it is truncated", len(raw)))
+			return
+		}
+		info.Nonce = raw[9:fernetHeaderLen]
+		info.PayloadLength = len(raw) - fernetHeaderLen - sha256.Size
+		if info.PayloadLength%aes.BlockSize != 0 {
+			info.Problems = append(info.Problems, "fernet ciphertext is not a multiple of the block size, it is truncated")
+		}
+	case isPrintable(raw) && info.Encoding == "raw":
+		info.Problems = append(info.Problems, "input is printable text and not a known ciphertext format")
+	default:
+		//Binary ciphertexts have no header, so anything else could be one
+		info.Format, info.Algorithm = FormatBinary, "AES-GCM"
+		inspectGCM(info, raw, 0)
+	}
+}
+
+//Fill nonce and payload length of nonce prefixed AES-GCM output, overhead is sealed data that is not payload
+func inspectGCM(info *CiphertextInfo, b []byte, overhead int) {
+	if len(b) < gcmNonceSize+gcmTagSize+overhead {
+		info.Problems = append(info.Problems, fmt.Sprintf("ciphertext of %d bytes is shorter than nonce and tag, it may be truncated", len(b)))
+		return
+	}
+
+	info.Nonce = b[:gcmNonceSize]
+	info.PayloadLength = len(b) - gcmNonceSize - gcmTagSize - overhead
+}
+
+//Binary ciphertexts can start with the version byte as well, but their nonce practically never continues with the zero high bytes of a fernet timestamp
+func isFernetHeader(b []byte) bool {
+	return len(b) >= 9 && b[0] == FernetVersion && b[1] == 0 && b[2] == 0 && b[3] == 0
+}
+
+func hasKnownMagic(b []byte) bool {
+	for _, magic := range []string{EnvelopeMagic, StreamMagic, TokenMagic, OpenSSLMagic} {
+		if bytes.HasPrefix(b, []byte(magic)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isBase64Text(b []byte) bool {
+	for _, c := range b {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("+/-_=", c) >= 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//Decode standard or URL-safe base64 with or without padding
+func decodeAnyBase64(s string) ([]byte, string, error) {
+	if strings.ContainsAny(s, "-_") {
+		if b, err := base64.URLEncoding.DecodeString(s); err == nil {
+			return b, "base64url", nil
+		}
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		return b, "base64url", err
+	}
+
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return b, "base64", nil
+	}
+	b, err := base64.RawStdEncoding.DecodeString(s)
+	return b, "base64", err
+}
+
+func isPrintable(b []byte) bool {
+	//Invalid UTF-8 is decoded as the replacement character, which is printable itself
+	if !utf8.Valid(b) {
+		return false
+	}
+
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func byteAt(b []byte, i int) byte {
+	if i < len(b) {
+		return b[i]
+	}
+
+	return 0
+}
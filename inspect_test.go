@@ -0,0 +1,120 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Inspect(t *testing.T) {
+	key := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+	e := NewEncryptionService(key)
+
+	binary, _ := e.EncryptStr("123Test")
+	text, _ := e.(TextEncrypter).EncryptStrText("123Test")
+	token, _ := e.(ExpiringEncrypter).EncryptStrWithTTL("123Test", time.Hour)
+	jwe, _ := e.(JWEEncrypter).EncryptJWE([]byte("123Test"), JWEAlgDir)
+
+	var stream bytes.Buffer
+	w, _ := e.(StreamEncrypter).NewEncryptWriter(&stream)
+	w.Write([]byte("123Test"))
+	w.Close()
+
+	priv, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	pub, _ := NewPublicKeyEncryptionService(priv.PublicKey())
+	envelope, _ := pub.EncryptStr("123Test")
+
+	fernetRaw, _ := base64.URLEncoding.DecodeString(fernetSpecToken)
+	fernet, _ := NewFernet(fernetSpecSecret)
+	longFernet, _ := fernet.EncryptStr(strings.Repeat("a", 20))
+	longFernetRaw, _ := base64.URLEncoding.DecodeString(longFernet)
+
+	tests := []struct {
+		name           string
+		ciphertext     []byte
+		wantFormat     string
+		wantEncoding   string
+		wantPayload    int
+		wantKeyID      string
+		wantRecipients int
+		wantProblems   bool
+	}{
+		{name: "binary", ciphertext: binary, wantFormat: FormatBinary, wantEncoding: "raw", wantPayload: 7},
+		{name: "binary as base64", ciphertext: []byte(base64.StdEncoding.EncodeToString(binary)), wantFormat: FormatBinary, wantEncoding: "base64", wantPayload: 7},
+		{name: "text", ciphertext: []byte(text), wantFormat: FormatText, wantEncoding: "text", wantPayload: 7, wantKeyID: "v1"},
+		{name: "truncated text", ciphertext: []byte(text[:20]), wantFormat: FormatText, wantEncoding: "text", wantKeyID: "v1", wantProblems: true},
+		{name: "expiring token", ciphertext: []byte(token), wantFormat: FormatToken, wantEncoding: "base64url", wantPayload: 7},
+		{name: "stream", ciphertext: stream.Bytes(), wantFormat: FormatStream, wantEncoding: "raw", wantPayload: 7},
+		{name: "envelope", ciphertext: envelope, wantFormat: FormatEnvelope, wantEncoding: "raw", wantPayload: 7, wantRecipients: 1},
+		{name: "jwe", ciphertext: []byte(jwe), wantFormat: FormatJWE, wantEncoding: "base64url", wantPayload: 7, wantKeyID: jwkThumbprint(key)},
+		{name: "fernet", ciphertext: []byte(fernetSpecToken), wantFormat: FormatFernet, wantEncoding: "base64url", wantPayload: 16},
+		{name: "truncated fernet", ciphertext: []byte(base64.URLEncoding.EncodeToString(fernetRaw[:50])), wantFormat: FormatFernet, wantEncoding: "base64url", wantProblems: true},
+		{name: "fernet truncated in block", ciphertext: []byte(base64.URLEncoding.EncodeToString(longFernetRaw[:len(longFernetRaw)-1])), wantFormat: FormatFernet, wantEncoding: "base64url", wantPayload: 31, wantProblems: true},
+		{name: "mangled base64", ciphertext: []byte(base64.StdEncoding.EncodeToString(binary)[1:]), wantFormat: FormatUnknown, wantEncoding: "raw", wantProblems: true},
+		{name: "plain text", ciphertext: []byte("not a ciphertext at all"), wantFormat: FormatUnknown, wantEncoding: "raw", wantProblems: true},
+		{name: "empty", ciphertext: nil, wantFormat: FormatUnknown, wantEncoding: "raw", wantProblems: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Inspect(tt.ciphertext)
+
+			if got.Format != tt.wantFormat || got.Encoding != tt.wantEncoding {
+				t.Errorf("Inspect() format = %v %v, want %v %v", got.Format, got.Encoding, tt.wantFormat, tt.wantEncoding)
+			}
+			if got.PayloadLength != tt.wantPayload {
+				t.Errorf("Inspect() payload length = %v, want %v", got.PayloadLength, tt.wantPayload)
+			}
+			if got.KeyID != tt.wantKeyID {
+				t.Errorf("Inspect() key ID = %v, want %v", got.KeyID, tt.wantKeyID)
+			}
+			if len(got.Recipients) != tt.wantRecipients {
+				t.Errorf("Inspect() recipients = %v, want %v", got.Recipients, tt.wantRecipients)
+			}
+			if (len(got.Problems) > 0) != tt.wantProblems {
+				t.Errorf("Inspect() problems = %v, wantProblems %v", got.Problems, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func Test_Diagnose(t *testing.T) {
+	oldKey := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+	newKey := bytes.Repeat([]byte{1}, 32)
+	keys := map[string][]byte{"old": oldKey, "new": newKey, "short": []byte("short")}
+
+	binary, _ := NewEncryptionService(newKey).EncryptStr("123Test")
+	token, _ := NewEncryptionService(oldKey).(ExpiringEncrypter).EncryptStrWithTTL("123Test", time.Hour)
+	other, _ := NewEncryptionService(bytes.Repeat([]byte{2}, 32)).EncryptStr("123Test")
+
+	var stream bytes.Buffer
+	w, _ := NewEncryptionService(oldKey).(StreamEncrypter).NewEncryptWriter(&stream)
+	w.Close()
+
+	tests := []struct {
+		name       string
+		ciphertext []byte
+		want       string
+	}{
+		{name: "binary with new key", ciphertext: binary, want: "new"},
+		{name: "token with old key", ciphertext: []byte(token), want: "old"},
+		{name: "empty stream with old key", ciphertext: stream.Bytes(), want: "old"},
+		{name: "fernet spec key", ciphertext: []byte(fernetSpecToken), want: ""},
+		{name: "unknown key", ciphertext: other, want: ""},
+		{name: "truncated", ciphertext: binary[:len(binary)-1], want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diagnose(tt.ciphertext, keys)
+			if got.MatchedKey != tt.want {
+				t.Errorf("Diagnose() matched key = %q, want %q, problems %v", got.MatchedKey, tt.want, got.Problems)
+			}
+			if tt.want == "" && len(got.Problems) == 0 {
+				t.Errorf("Diagnose() expected problems when no key matches")
+			}
+		})
+	}
+}
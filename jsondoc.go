@@ -0,0 +1,509 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Define JSON document constants
+const (
+	JSONDocumentMetadataField = "_globe_encryption"
+	JSONDocumentVersion       = 1
+	JSONSelectorRegexPrefix   = "regex:"
+	jsonEncryptedPrefix       = "ENC["
+	jsonDocumentLabel         = "globe-protocol/encryption json document"
+)
+
+//Metadata block added to encrypted JSON documents, only the values at Paths are decrypted so that plain strings looking like ENC[...] stay as they are
+type jsonDocumentMetadata struct {
+	Version    int       `json:"version"`
+	KeyVersion uint32    `json:"key_version,omitempty"`
+	Selectors  []string  `json:"selectors"`
+	Paths      []string  `json:"paths"`
+	Modified   time.Time `json:"modified"`
+	MAC        string    `json:"mac"`
+}
+
+//Parsed JSON value that keeps the order of object keys
+type jsonNode struct {
+	keys   []string
+	values []*jsonNode
+	object bool
+	array  bool
+	leaf   interface{}
+}
+
+//Single step of a selector path, an empty key with index -1 matches everything
+type jsonSegment struct {
+	key   string
+	index int
+}
+
+type jsonSelector struct {
+	path  []jsonSegment
+	regex *regexp.Regexp
+}
+
+//Encrypt the values at the given JSON paths like $.db.password or $.users[*].ssn, or under keys matching regex:<expression>, in place
+func (e *encryptionService) EncryptJSONDocument(doc []byte, selectors []string) ([]byte, error) {
+	root, err := parseJSONDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	if root.get(JSONDocumentMetadataField) != nil {
+		return nil, fmt.Errorf("document already contains a %s field, it may already be encrypted", JSONDocumentMetadataField)
+	}
+
+	parsed, err := parseJSONSelectors(selectors)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	if err := e.encryptJSONNode(root, nil, parsed, false, &paths); err != nil {
+		return nil, err
+	}
+
+	meta := jsonDocumentMetadata{
+		Version:    JSONDocumentVersion,
+		KeyVersion: e.keyVersion(),
+		Selectors:  selectors,
+		Paths:      paths,
+		Modified:   e.now().UTC().Truncate(time.Second),
+	}
+	if meta.Selectors == nil {
+		meta.Selectors = []string{}
+	}
+
+	//Like sops the MAC is a digest of the document that is encrypted, so it works with every kind of service
+	meta.MAC, err = e.EncryptStrText(string(jsonDocumentDigest(root, meta)))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	root.keys = append(root.keys, JSONDocumentMetadataField)
+	root.values = append(root.values, &jsonNode{leaf: json.RawMessage(b)})
+
+	return root.marshal(bytes.Contains(doc, []byte("\n")))
+}
+
+//Verify and decrypt document created by EncryptJSONDocument, the metadata block is removed
+func (e *encryptionService) DecryptJSONDocument(doc []byte) ([]byte, error) {
+	root, err := parseJSONDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	metaNode := root.get(JSONDocumentMetadataField)
+	if metaNode == nil {
+		return nil, fmt.Errorf("document does not contain a %s metadata block", JSONDocumentMetadataField)
+	}
+	root.remove(JSONDocumentMetadataField)
+
+	metaJSON, err := metaNode.marshal(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta jsonDocumentMetadata
+	if err := json.Unmarshal(metaJSON, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse %s metadata block, the following error occured: %s", JSONDocumentMetadataField, err)
+	}
+	if meta.Version != JSONDocumentVersion {
+		return nil, fmt.Errorf("document version %d is not supported", meta.Version)
+	}
+
+	digest, err := e.DecryptStrText(meta.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt document MAC, the following error occured: %s", err)
+	}
+	if !hmac.Equal([]byte(digest), jsonDocumentDigest(root, meta)) {
+		return nil, errors.New("document MAC does not match, values were modified, added or removed")
+	}
+
+	paths := make(map[string]bool, len(meta.Paths))
+	for _, path := range meta.Paths {
+		paths[path] = true
+	}
+	if err := e.decryptJSONNode(root, nil, paths); err != nil {
+		return nil, err
+	}
+
+	return root.marshal(bytes.Contains(doc, []byte("\n")))
+}
+
+//Encrypt matching values and add their paths to encrypted
+func (e *encryptionService) encryptJSONNode(node *jsonNode, path []jsonSegment, selectors []jsonSelector, encrypt bool, encrypted *[]string) error {
+	switch {
+	case node.object, node.array:
+		for i, child := range node.values {
+			seg := jsonSegment{key: "", index: i}
+			if node.object {
+				seg = jsonSegment{key: node.keys[i], index: -1}
+			}
+			childPath := append(append([]jsonSegment{}, path...), seg)
+
+			if err := e.encryptJSONNode(child, childPath, selectors, encrypt || matchJSONSelectors(selectors, childPath), encrypted); err != nil {
+				return err
+			}
+		}
+	case encrypt && node.leaf != nil:
+		typ, plain := "str", ""
+		switch v := node.leaf.(type) {
+		case string:
+			plain = v
+		case json.Number:
+			typ, plain = "num", v.String()
+		case bool:
+			typ, plain = "bool", strconv.FormatBool(v)
+		}
+
+		text, err := e.EncryptStrText(plain)
+		if err != nil {
+			return err
+		}
+		node.leaf = jsonEncryptedPrefix + typ + "," + text + "]"
+		*encrypted = append(*encrypted, formatJSONPath(path))
+	}
+
+	return nil
+}
+
+//Decrypt the values at the encrypted paths
+func (e *encryptionService) decryptJSONNode(node *jsonNode, path []jsonSegment, encrypted map[string]bool) error {
+	for i, child := range node.values {
+		seg := jsonSegment{key: "", index: i}
+		if node.object {
+			seg = jsonSegment{key: node.keys[i], index: -1}
+		}
+		if err := e.decryptJSONNode(child, append(append([]jsonSegment{}, path...), seg), encrypted); err != nil {
+			return err
+		}
+	}
+
+	if node.object || node.array || !encrypted[formatJSONPath(path)] {
+		return nil
+	}
+
+	s, ok := node.leaf.(string)
+	if !ok || !strings.HasPrefix(s, jsonEncryptedPrefix) || !strings.HasSuffix(s, "]") {
+		return fmt.Errorf("value at %s is listed as encrypted but is not an encrypted value", formatJSONPath(path))
+	}
+
+	typ, text, found := strings.Cut(s[len(jsonEncryptedPrefix):len(s)-1], ",")
+	if !found {
+		return fmt.Errorf("encrypted value at %s is malformed", formatJSONPath(path))
+	}
+
+	plain, err := e.DecryptStrText(text)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt value at %s, the following error occured: %s", formatJSONPath(path), err)
+	}
+
+	switch typ {
+	case "str":
+		node.leaf = plain
+	case "num":
+		node.leaf = json.Number(plain)
+	case "bool":
+		node.leaf, err = strconv.ParseBool(plain)
+	default:
+		err = fmt.Errorf("type %q is not supported", typ)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore value at %s, the following error occured: %s", formatJSONPath(path), err)
+	}
+
+	return nil
+}
+
+//Calculate digest over every value with its path and the metadata that is not part of the MAC itself
+func jsonDocumentDigest(root *jsonNode, meta jsonDocumentMetadata) []byte {
+	doc := &documentMAC{}
+	encryptedPaths := make(map[string]bool, len(meta.Paths))
+	for _, path := range meta.Paths {
+		encryptedPaths[path] = true
+	}
+
+	var walk func(node *jsonNode, path []jsonSegment)
+	walk = func(node *jsonNode, path []jsonSegment) {
+		if !node.object && !node.array {
+			leaf, _ := json.Marshal(node.leaf)
+			doc.add(formatJSONPath(path), encryptedPaths[formatJSONPath(path)], leaf)
+			return
+		}
+
+		//Empty containers are values as well
+		if len(node.values) == 0 {
+			doc.add(formatJSONPath(path), false, []byte(fmt.Sprint(node.object)))
+		}
+		for i, child := range node.values {
+			seg := jsonSegment{key: "", index: i}
+			if node.object {
+				seg = jsonSegment{key: node.keys[i], index: -1}
+			}
+			walk(child, append(append([]jsonSegment{}, path...), seg))
+		}
+	}
+	walk(root, nil)
+
+	selectors, _ := json.Marshal(meta.Selectors)
+	doc.add("\x00selectors", false, selectors)
+	paths, _ := json.Marshal(meta.Paths)
+	doc.add("\x00paths", false, paths)
+	doc.add("\x00modified", false, []byte(meta.Modified.Format(time.RFC3339)))
+	doc.add("\x00key_version", false, []byte(strconv.FormatUint(uint64(meta.KeyVersion), 10)))
+
+	//The label is a public domain separator, the digest is kept secret by encrypting it
+	return doc.sum([]byte(jsonDocumentLabel))
+}
+
+//Parse selectors, paths start with $ and use .key, [index], .* and [*]
+func parseJSONSelectors(selectors []string) ([]jsonSelector, error) {
+	parsed := make([]jsonSelector, 0, len(selectors))
+	for _, s := range selectors {
+		if strings.HasPrefix(s, JSONSelectorRegexPrefix) {
+			re, err := regexp.Compile(strings.TrimPrefix(s, JSONSelectorRegexPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("selector %q is not a valid regular expression, the following error occured: %s", s, err)
+			}
+			parsed = append(parsed, jsonSelector{regex: re})
+			continue
+		}
+
+		if !strings.HasPrefix(s, "$") || s == "$" {
+			return nil, fmt.Errorf("selector %q should be a path starting with $. or a regex: expression", s)
+		}
+
+		var path []jsonSegment
+		rest := s[1:]
+		for rest != "" {
+			switch {
+			case strings.HasPrefix(rest, "["):
+				end := strings.IndexByte(rest, ']')
+				if end < 0 {
+					return nil, fmt.Errorf("selector %q has an unclosed [", s)
+				}
+
+				if inner := rest[1:end]; inner == "*" {
+					path = append(path, jsonSegment{index: -1})
+				} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+					path = append(path, jsonSegment{index: index})
+				} else {
+					return nil, fmt.Errorf("selector %q has invalid index %q", s, inner)
+				}
+				rest = rest[end+1:]
+			case strings.HasPrefix(rest, "."):
+				rest = rest[1:]
+				end := strings.IndexAny(rest, ".[")
+				if end < 0 {
+					end = len(rest)
+				}
+				if end == 0 {
+					return nil, fmt.Errorf("selector %q has an empty key", s)
+				}
+
+				key := rest[:end]
+				if key == "*" {
+					path = append(path, jsonSegment{index: -1})
+				} else {
+					path = append(path, jsonSegment{key: key, index: -1})
+				}
+				rest = rest[end:]
+			default:
+				return nil, fmt.Errorf("selector %q is not a valid path", s)
+			}
+		}
+
+		parsed = append(parsed, jsonSelector{path: path})
+	}
+
+	return parsed, nil
+}
+
+func matchJSONSelectors(selectors []jsonSelector, path []jsonSegment) bool {
+	last := path[len(path)-1]
+	for _, s := range selectors {
+		if s.regex != nil {
+			if last.index == -1 && s.regex.MatchString(last.key) {
+				return true
+			}
+			continue
+		}
+
+		if len(s.path) != len(path) {
+			continue
+		}
+
+		match := true
+		for i, seg := range s.path {
+			switch {
+			case seg.key == "" && seg.index == -1:
+			case seg.key != "":
+				match = match && path[i].index == -1 && path[i].key == seg.key
+			default:
+				match = match && path[i].index == seg.index
+			}
+		}
+		if match {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatJSONPath(path []jsonSegment) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, seg := range path {
+		if seg.index >= 0 {
+			fmt.Fprintf(&b, "[%d]", seg.index)
+		} else {
+			key, _ := json.Marshal(seg.key)
+			fmt.Fprintf(&b, "[%s]", key)
+		}
+	}
+
+	return b.String()
+}
+
+//Parse JSON object keeping the order of keys
+func parseJSONDocument(doc []byte) (*jsonNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	root, err := parseJSONNode(dec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON document, the following error occured: %s", err)
+	}
+	if !root.object {
+		return nil, errors.New("JSON document should be an object")
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("JSON document should contain a single object")
+	}
+
+	return root, nil
+}
+
+func parseJSONNode(dec *json.Decoder) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return &jsonNode{leaf: tok}, nil
+	}
+
+	node := &jsonNode{object: delim == '{', array: delim == '['}
+	for dec.More() {
+		if node.object {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			node.keys = append(node.keys, keyTok.(string))
+		}
+
+		child, err := parseJSONNode(dec)
+		if err != nil {
+			return nil, err
+		}
+		node.values = append(node.values, child)
+	}
+
+	//Read closing delimiter
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func (n *jsonNode) get(key string) *jsonNode {
+	for i, k := range n.keys {
+		if k == key {
+			return n.values[i]
+		}
+	}
+
+	return nil
+}
+
+func (n *jsonNode) remove(key string) {
+	for i, k := range n.keys {
+		if k == key {
+			n.keys = append(n.keys[:i], n.keys[i+1:]...)
+			n.values = append(n.values[:i], n.values[i+1:]...)
+			return
+		}
+	}
+}
+
+func (n *jsonNode) marshal(indent bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := n.write(&buf); err != nil {
+		return nil, err
+	}
+
+	if !indent {
+		return buf.Bytes(), nil
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+
+	return out.Bytes(), nil
+}
+
+func (n *jsonNode) write(buf *bytes.Buffer) error {
+	if !n.object && !n.array {
+		b, err := json.Marshal(n.leaf)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		return nil
+	}
+
+	open, close := byte('['), byte(']')
+	if n.object {
+		open, close = '{', '}'
+	}
+
+	buf.WriteByte(open)
+	for i, child := range n.values {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if n.object {
+			key, _ := json.Marshal(n.keys[i])
+			buf.Write(key)
+			buf.WriteByte(':')
+		}
+		if err := child.write(buf); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(close)
+
+	return nil
+}
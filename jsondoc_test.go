@@ -0,0 +1,334 @@
+package encryption
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const jsonDocumentSrc = `{
+  "name": "billing",
+  "db": {
+    "host": "db.internal",
+    "port": 5432,
+    "password": "hunter2"
+  },
+  "users": [
+    {
+      "email": "alice@example.com",
+      "ssn": "078-05-1120",
+      "admin": true
+    },
+    {
+      "email": "bob@example.com",
+      "ssn": "219-09-9999",
+      "admin": false
+    }
+  ],
+  "api_token": "abc123",
+  "tags": [],
+  "parent": null
+}
+`
+
+func Test_encryptionService_EncryptJSONDocument(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		name          string
+		selectors     []string
+		wantEncrypted []string
+		wantPlain     []string
+		wantErr       bool
+	}{
+		{
+			name:          "single path",
+			selectors:     []string{"$.db.password"},
+			wantEncrypted: []string{"hunter2"},
+			wantPlain:     []string{"db.internal", "alice@example.com", "abc123"},
+		},
+		{
+			name:          "array wildcard",
+			selectors:     []string{"$.users[*].ssn"},
+			wantEncrypted: []string{"078-05-1120", "219-09-9999"},
+			wantPlain:     []string{"alice@example.com", "hunter2"},
+		},
+		{
+			name:          "array index",
+			selectors:     []string{"$.users[1].ssn"},
+			wantEncrypted: []string{"219-09-9999"},
+			wantPlain:     []string{"078-05-1120"},
+		},
+		{
+			name:          "whole object",
+			selectors:     []string{"$.db"},
+			wantEncrypted: []string{"db.internal", "5432", "hunter2"},
+			wantPlain:     []string{"alice@example.com"},
+		},
+		{
+			name:          "key wildcard",
+			selectors:     []string{"$.*.password", "$.users.*.email"},
+			wantEncrypted: []string{"hunter2", "alice@example.com", "bob@example.com"},
+			wantPlain:     []string{"db.internal", "078-05-1120"},
+		},
+		{
+			name:          "regex",
+			selectors:     []string{"regex:^(password|api_token|ssn)$"},
+			wantEncrypted: []string{"hunter2", "abc123", "078-05-1120", "219-09-9999"},
+			wantPlain:     []string{"db.internal", "alice@example.com"},
+		},
+		{
+			name:      "invalid regex",
+			selectors: []string{"regex:("},
+			wantErr:   true,
+		},
+		{
+			name:      "path without $",
+			selectors: []string{"db.password"},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid index",
+			selectors: []string{"$.users[x]"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEncryptionService(key)
+
+			got, err := e.(DocumentEncrypter).EncryptJSONDocument([]byte(jsonDocumentSrc), tt.selectors)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncryptJSONDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for _, s := range tt.wantEncrypted {
+				if strings.Contains(string(got), s) {
+					t.Errorf("EncryptJSONDocument() output contains %q in plaintext", s)
+				}
+			}
+			for _, s := range tt.wantPlain {
+				if !strings.Contains(string(got), s) {
+					t.Errorf("EncryptJSONDocument() output does not contain %q", s)
+				}
+			}
+			if !strings.Contains(string(got), `"ENC[str,globe:v1:`) {
+				t.Errorf("EncryptJSONDocument() output has no encrypted values: %s", got)
+			}
+
+			decrypted, err := e.(DocumentEncrypter).DecryptJSONDocument(got)
+			if err != nil {
+				t.Fatalf("DecryptJSONDocument() error = %v", err)
+			}
+			if string(decrypted) != jsonDocumentSrc {
+				t.Errorf("DecryptJSONDocument() = %s, want %s", decrypted, jsonDocumentSrc)
+			}
+		})
+	}
+}
+
+func Test_encryptionService_EncryptJSONDocument_Compact(t *testing.T) {
+	e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+	src := `{"b":1.50,"a":{"n":-3e2,"ok":false}}`
+
+	got, err := e.(DocumentEncrypter).EncryptJSONDocument([]byte(src), []string{"$.a", "$.b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(got), "\n") {
+		t.Errorf("EncryptJSONDocument() should keep compact input compact, got %s", got)
+	}
+
+	if _, err := e.(DocumentEncrypter).EncryptJSONDocument(got, []string{"$.b"}); err == nil {
+		t.Errorf("EncryptJSONDocument() on encrypted document should fail")
+	}
+
+	decrypted, err := e.(DocumentEncrypter).DecryptJSONDocument(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Numbers are restored exactly as they were written
+	if string(decrypted) != src {
+		t.Errorf("DecryptJSONDocument() = %s, want %s", decrypted, src)
+	}
+}
+
+//Plain values that look like encrypted values or metadata are left alone
+func Test_encryptionService_EncryptJSONDocument_PlainLookalikes(t *testing.T) {
+	e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+	src := `{"globe":{"region":"eu"},"note":"ENC[str,not encrypted]","password":"ENC[str,hunter2]"}`
+
+	got, err := e.(DocumentEncrypter).EncryptJSONDocument([]byte(src), []string{"$.password"})
+	if err != nil {
+		t.Fatalf("EncryptJSONDocument() error = %v", err)
+	}
+	if !strings.Contains(string(got), `"note":"ENC[str,not encrypted]"`) || strings.Contains(string(got), "hunter2") {
+		t.Errorf("EncryptJSONDocument() = %s", got)
+	}
+
+	decrypted, err := e.(DocumentEncrypter).DecryptJSONDocument(got)
+	if err != nil {
+		t.Fatalf("DecryptJSONDocument() error = %v", err)
+	}
+	if string(decrypted) != src {
+		t.Errorf("DecryptJSONDocument() = %s, want %s", decrypted, src)
+	}
+}
+
+func Test_encryptionService_DecryptJSONDocument(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	e := NewEncryptionService(key)
+
+	encrypted, err := e.(DocumentEncrypter).EncryptJSONDocument([]byte(jsonDocumentSrc), []string{"$.db.password", "$.users[*].ssn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		service EncryptionService
+		tamper  func(doc map[string]interface{})
+		wantErr bool
+	}{
+		{
+			name:    "re-encoded document",
+			service: e,
+			tamper:  func(doc map[string]interface{}) {},
+		},
+		{
+			name:    "plaintext value modified",
+			service: e,
+			tamper: func(doc map[string]interface{}) {
+				doc["db"].(map[string]interface{})["host"] = "evil.example.com"
+			},
+			wantErr: true,
+		},
+		{
+			name:    "encrypted values swapped",
+			service: e,
+			tamper: func(doc map[string]interface{}) {
+				users := doc["users"].([]interface{})
+				a, b := users[0].(map[string]interface{}), users[1].(map[string]interface{})
+				a["ssn"], b["ssn"] = b["ssn"], a["ssn"]
+			},
+			wantErr: true,
+		},
+		{
+			name:    "value removed",
+			service: e,
+			tamper: func(doc map[string]interface{}) {
+				delete(doc, "api_token")
+			},
+			wantErr: true,
+		},
+		{
+			name:    "value added",
+			service: e,
+			tamper: func(doc map[string]interface{}) {
+				doc["extra"] = "x"
+			},
+			wantErr: true,
+		},
+		{
+			name:    "selectors changed",
+			service: e,
+			tamper: func(doc map[string]interface{}) {
+				doc[JSONDocumentMetadataField].(map[string]interface{})["selectors"] = []string{}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "encrypted paths changed",
+			service: e,
+			tamper: func(doc map[string]interface{}) {
+				doc[JSONDocumentMetadataField].(map[string]interface{})["paths"] = []string{`$["db"]["password"]`}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "metadata removed",
+			service: e,
+			tamper: func(doc map[string]interface{}) {
+				delete(doc, JSONDocumentMetadataField)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "wrong key",
+			service: NewEncryptionService([]byte("fedcba9876543210fedcba9876543210")),
+			tamper:  func(doc map[string]interface{}) {},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc map[string]interface{}
+			if err := json.Unmarshal(encrypted, &doc); err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(doc)
+			b, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := tt.service.(DocumentEncrypter).DecryptJSONDocument(b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecryptJSONDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var gotDoc, wantDoc map[string]interface{}
+			if err := json.Unmarshal(got, &gotDoc); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(jsonDocumentSrc), &wantDoc); err != nil {
+				t.Fatal(err)
+			}
+			gotJSON, _ := json.Marshal(gotDoc)
+			wantJSON, _ := json.Marshal(wantDoc)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("DecryptJSONDocument() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func Test_parseJSONDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr bool
+	}{
+		{name: "object", doc: `{"a":[1,{"b":null}]}`},
+		{name: "array", doc: `[1,2]`, wantErr: true},
+		{name: "string", doc: `"a"`, wantErr: true},
+		{name: "trailing data", doc: `{"a":1}{"b":2}`, wantErr: true},
+		{name: "invalid", doc: `{"a":}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseJSONDocument([]byte(tt.doc))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJSONDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			b, err := got.marshal(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.doc {
+				t.Errorf("jsonNode.marshal() = %s, want %s", b, tt.doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//Define supported JWE algorithms, see RFC 7516 and RFC 7518
+const (
+	JWEAlgDir     = "dir"
+	JWEAlgA256KW  = "A256KW"
+	JWEEncA256GCM = "A256GCM"
+	jweKeySize    = 32
+)
+
+type jweHeader struct {
+	Alg  string   `json:"alg"`
+	Enc  string   `json:"enc"`
+	Kid  string   `json:"kid,omitempty"`
+	Zip  string   `json:"zip,omitempty"`
+	Crit []string `json:"crit,omitempty"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	K   string `json:"k"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+}
+
+var aesKeyWrapIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+//Get the key of the service, JWE is only supported by services created with a 32 byte symmetric key
+func (e *encryptionService) jweKey() ([]byte, error) {
+	if len(e.recipients) > 0 || len(e.key) != jweKeySize {
+		return nil, errors.New("JWE requires a service with a 32 byte symmetric key")
+	}
+
+	return e.key, nil
+}
+
+//Get the key ID of a key, the ID of an imported JWK is kept so that tokens of its other holders are recognized
+func (e *encryptionService) jweKeyID(key []byte) string {
+	if e.jwkID != "" && bytes.Equal(key, e.key) {
+		return e.jwkID
+	}
+
+	return jwkThumbprint(key)
+}
+
+//Get the key version of the keyring with the given JWK thumbprint, nil if there is none
+func (e *encryptionService) jweKeyByID(kid string) []byte {
+	for _, key := range e.keyring.Keys {
+		if len(key) == jweKeySize && jwkThumbprint(key) == kid {
+			return key
+		}
+	}
+
+	return nil
+}
+
+//Encrypt []byte to a JWE in compact serialization, alg should be JWEAlgDir or JWEAlgA256KW
+func (e *encryptionService) EncryptJWE(b []byte, alg string) (string, error) {
+	key, err := e.jweKey()
+	if err != nil {
+		return "", err
+	}
+
+	var cek, encryptedKey []byte
+	switch alg {
+	case JWEAlgDir:
+		cek = key
+	case JWEAlgA256KW:
+		cek = make([]byte, jweKeySize)
+		if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+			return "", err
+		}
+
+		encryptedKey, err = aesKeyWrap(key, cek)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("JWE algorithm %q is not supported", alg)
+	}
+
+	header, err := json.Marshal(jweHeader{Alg: alg, Enc: JWEEncA256GCM, Kid: e.jweKeyID(key)})
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(header)
+
+	aesGCM, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	//The encoded protected header is the additional authenticated data
+	sealed := aesGCM.Seal(nil, iv, b, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-aesGCM.Overhead()], sealed[len(sealed)-aesGCM.Overhead():]
+
+	return strings.Join([]string{
+		protected,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+//Decrypt JWE in compact serialization that uses dir or A256KW with A256GCM
+func (e *encryptionService) DecryptJWE(token string) ([]byte, error) {
+	key, err := e.jweKey()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, errors.New("JWE compact serialization should have 5 parts")
+	}
+
+	decoded := make([][]byte, len(parts))
+	for i, part := range parts {
+		decoded[i], err = base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode JWE part %d, the following error occured: %s", i+1, err)
+		}
+	}
+
+	var header jweHeader
+	if err := json.Unmarshal(decoded[0], &header); err != nil {
+		return nil, fmt.Errorf("failed to parse JWE header, the following error occured: %s", err)
+	}
+	if header.Enc != JWEEncA256GCM {
+		return nil, fmt.Errorf("JWE encryption %q is not supported", header.Enc)
+	}
+	if header.Zip != "" || len(header.Crit) > 0 {
+		return nil, errors.New("JWE compression and critical header parameters are not supported")
+	}
+	if header.Kid != "" && header.Kid != e.jweKeyID(key) && header.Kid != jwkThumbprint(key) {
+		//Tokens created before a keyring was rotated name an older version
+		if key = e.jweKeyByID(header.Kid); key == nil {
+			return nil, fmt.Errorf("JWE is encrypted with key %q", header.Kid)
+		}
+	}
+
+	var cek []byte
+	switch header.Alg {
+	case JWEAlgDir:
+		if len(decoded[1]) != 0 {
+			return nil, errors.New("JWE with dir algorithm should not contain an encrypted key")
+		}
+		cek = key
+	case JWEAlgA256KW:
+		cek, err = aesKeyUnwrap(key, decoded[1])
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("JWE algorithm %q is not supported", header.Alg)
+	}
+
+	//newGCM accepts AES-128 and AES-192 keys as well, which A256GCM does not allow
+	if len(cek) != jweKeySize {
+		return nil, fmt.Errorf("JWE content encryption key should be %d bytes for %s, got %d", jweKeySize, JWEEncA256GCM, len(cek))
+	}
+
+	aesGCM, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded[2]) != aesGCM.NonceSize() || len(decoded[4]) != aesGCM.Overhead() {
+		return nil, errors.New("JWE has an invalid initialization vector or authentication tag")
+	}
+
+	plain, err := aesGCM.Open(nil, decoded[2], append(decoded[3], decoded[4]...), []byte(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt JWE, external cipher package returned the following error: %s", err)
+	}
+
+	return plain, nil
+}
+
+//Export the key of the service as a symmetric JWK so that it can be shared with other JWE implementations
+func (e *encryptionService) ExportJWK() ([]byte, error) {
+	key, err := e.jweKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jwk{
+		Kty: "oct",
+		K:   base64.RawURLEncoding.EncodeToString(key),
+		Kid: e.jweKeyID(key),
+	})
+}
+
+//Create encryption service from a symmetric JWK holding a 32 byte key
+func NewEncryptionServiceFromJWK(b []byte) (EncryptionService, error) {
+	var k jwk
+	if err := json.Unmarshal(b, &k); err != nil {
+		return nil, fmt.Errorf("failed to parse JWK, the following error occured: %s", err)
+	}
+	if k.Kty != "oct" {
+		return nil, fmt.Errorf("JWK key type %q is not supported, only oct keys can be imported", k.Kty)
+	}
+
+	key, err := base64.RawURLEncoding.DecodeString(k.K)
+	if err != nil || len(key) != jweKeySize {
+		return nil, errors.New("JWK should contain a 32 byte key")
+	}
+
+	return &encryptionService{
+		key:   key,
+		jwkID: k.Kid,
+	}, nil
+}
+
+//Get the RFC 7638 thumbprint of a symmetric key, used as key ID
+func jwkThumbprint(key []byte) string {
+	sum := sha256.Sum256([]byte(`{"k":"` + base64.RawURLEncoding.EncodeToString(key) + `","kty":"oct"}`))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+//Wrap key as described in RFC 3394
+func aesKeyWrap(kek, plain []byte) ([]byte, error) {
+	if len(plain)%8 != 0 || len(plain) < 16 {
+		return nil, errors.New("key to wrap should be a multiple of 8 bytes and at least 16 bytes")
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher, external aes package returned the following error: %s", err)
+	}
+
+	n := len(plain) / 8
+	a := append([]byte{}, aesKeyWrapIV...)
+	r := append([]byte{}, plain...)
+	buf := make([]byte, aes.BlockSize)
+
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(buf, a)
+			copy(buf[8:], r[i*8:(i+1)*8])
+			block.Encrypt(buf, buf)
+
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(buf[:8])^t)
+			copy(r[i*8:], buf[8:])
+		}
+	}
+
+	return append(a, r...), nil
+}
+
+//Unwrap key as described in RFC 3394
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, errors.New("wrapped key should be a multiple of 8 bytes and at least 24 bytes")
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher, external aes package returned the following error: %s", err)
+	}
+
+	n := len(wrapped)/8 - 1
+	a := append([]byte{}, wrapped[:8]...)
+	r := append([]byte{}, wrapped[8:]...)
+	buf := make([]byte, aes.BlockSize)
+
+	for j := 5; j >= 0; j-- {
+		for i := n - 1; i >= 0; i-- {
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(buf, binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], r[i*8:(i+1)*8])
+			block.Decrypt(buf, buf)
+
+			copy(a, buf[:8])
+			copy(r[i*8:], buf[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a, aesKeyWrapIV) != 1 {
+		return nil, errors.New("failed to unwrap key, integrity check failed")
+	}
+
+	return r, nil
+}
+
@@ -0,0 +1,252 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_aesKeyWrap(t *testing.T) {
+	//Vector from section 4.6 of RFC 3394, 256 bit key data with 256 bit KEK
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F")
+	plain, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F")
+	want, _ := hex.DecodeString("28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21")
+
+	got, err := aesKeyWrap(kek, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("aesKeyWrap() = %X, want %X", got, want)
+	}
+
+	unwrapped, err := aesKeyUnwrap(kek, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, plain) {
+		t.Errorf("aesKeyUnwrap() = %X, want %X", unwrapped, plain)
+	}
+
+	got[0] ^= 1
+	if _, err := aesKeyUnwrap(kek, got); err == nil {
+		t.Errorf("expected error while unwrapping modified key")
+	}
+}
+
+func Test_encryptionService_EncryptJWE(t *testing.T) {
+	key := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+	otherKey := bytes.Repeat([]byte{7}, 32)
+
+	tests := []struct {
+		name       string
+		alg        string
+		decryptKey []byte
+		tamper     func(token string) string
+		wantErr    bool
+	}{
+		{
+			name:       "dir",
+			alg:        JWEAlgDir,
+			decryptKey: key,
+		},
+		{
+			name:       "A256KW",
+			alg:        JWEAlgA256KW,
+			decryptKey: key,
+		},
+		{
+			name:       "unsupported algorithm",
+			alg:        "RSA-OAEP",
+			decryptKey: key,
+			wantErr:    true,
+		},
+		{
+			name:       "different key",
+			alg:        JWEAlgA256KW,
+			decryptKey: otherKey,
+			wantErr:    true,
+		},
+		{
+			name:       "modified header",
+			alg:        JWEAlgDir,
+			decryptKey: key,
+			tamper: func(token string) string {
+				parts := strings.Split(token, ".")
+				parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"dir","enc":"A256GCM","cty":"JWT"}`))
+				return strings.Join(parts, ".")
+			},
+			wantErr: true,
+		},
+		{
+			name:       "modified ciphertext",
+			alg:        JWEAlgA256KW,
+			decryptKey: key,
+			tamper: func(token string) string {
+				parts := strings.Split(token, ".")
+				ciphertext, _ := base64.RawURLEncoding.DecodeString(parts[3])
+				ciphertext[0] ^= 1
+				parts[3] = base64.RawURLEncoding.EncodeToString(ciphertext)
+				return strings.Join(parts, ".")
+			},
+			wantErr: true,
+		},
+		{
+			name:       "missing part",
+			alg:        JWEAlgDir,
+			decryptKey: key,
+			tamper: func(token string) string {
+				return token[:strings.LastIndex(token, ".")]
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := NewEncryptionService(key).(JWEEncrypter).EncryptJWE([]byte("partner payload"), tt.alg)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("encryptionService.EncryptJWE() error = %v", err)
+				}
+				return
+			}
+
+			if tt.tamper != nil {
+				token = tt.tamper(token)
+			}
+
+			got, err := NewEncryptionService(tt.decryptKey).(JWEEncrypter).DecryptJWE(token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.DecryptJWE() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && string(got) != "partner payload" {
+				t.Errorf("encryptionService.DecryptJWE() = %s, want %s", got, "partner payload")
+			}
+		})
+	}
+}
+
+func Test_encryptionService_ExportJWK(t *testing.T) {
+	key := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+
+	exported, err := NewEncryptionService(key).(JWEEncrypter).ExportJWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var k map[string]string
+	if err := json.Unmarshal(exported, &k); err != nil {
+		t.Fatal(err)
+	}
+	if k["kty"] != "oct" || k["k"] != base64.RawURLEncoding.EncodeToString(key) || k["kid"] == "" {
+		t.Errorf("encryptionService.ExportJWK() = %s", exported)
+	}
+
+	imported, err := NewEncryptionServiceFromJWK(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := imported.(JWEEncrypter).EncryptJWE([]byte("partner payload"), JWEAlgDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEncryptionService(key).(JWEEncrypter).DecryptJWE(token); err != nil {
+		t.Errorf("expected token from imported key to decrypt with original key, got error %v", err)
+	}
+
+	if _, err := NewEncryptionServiceFromJWK([]byte(`{"kty":"RSA","n":"AQAB"}`)); err == nil {
+		t.Errorf("expected error while importing RSA JWK")
+	}
+	if _, err := NewEncryptionServiceFromJWK([]byte(`{"kty":"oct","k":"AAAA"}`)); err == nil {
+		t.Errorf("expected error while importing short key")
+	}
+}
+
+//Imported JWKs keep their own key ID, so tokens of partners naming it can be decrypted
+func Test_NewEncryptionServiceFromJWK_ForeignKid(t *testing.T) {
+	key := bytes.Repeat([]byte{9}, 32)
+	partnerJWK := []byte(`{"kty":"oct","kid":"partner-1","k":"` + base64.RawURLEncoding.EncodeToString(key) + `"}`)
+
+	imported, err := NewEncryptionServiceFromJWK(partnerJWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Token as the partner creates it, with its own key ID
+	protected := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"dir","enc":"A256GCM","kid":"partner-1"}`))
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := bytes.Repeat([]byte{1}, aesGCM.NonceSize())
+	sealed := aesGCM.Seal(nil, iv, []byte("partner payload"), []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-aesGCM.Overhead()], sealed[len(sealed)-aesGCM.Overhead():]
+	token := strings.Join([]string{
+		protected,
+		"",
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, ".")
+
+	got, err := imported.(JWEEncrypter).DecryptJWE(token)
+	if err != nil || string(got) != "partner payload" {
+		t.Errorf("DecryptJWE() of token with foreign kid = %q, %v", got, err)
+	}
+
+	//Tokens and exports of the imported key name the partner key ID
+	own, err := imported.(JWEEncrypter).EncryptJWE([]byte("reply"), JWEAlgA256KW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := Inspect([]byte(own)); info.KeyID != "partner-1" {
+		t.Errorf("EncryptJWE() kid = %q, want partner-1", info.KeyID)
+	}
+	exported, err := imported.(JWEEncrypter).ExportJWK()
+	if err != nil || !strings.Contains(string(exported), `"kid":"partner-1"`) {
+		t.Errorf("ExportJWK() = %s, %v", exported, err)
+	}
+
+	if _, err := NewEncryptionService(key).(JWEEncrypter).DecryptJWE(own); err == nil {
+		t.Errorf("DecryptJWE() of token naming an unknown key should fail")
+	}
+}
+
+//A wrapped AES-128 key must not be accepted for A256GCM
+func Test_encryptionService_DecryptJWE_ShortCEK(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	e := NewEncryptionService(key)
+
+	cek := bytes.Repeat([]byte{1}, 16)
+	encryptedKey, err := aesKeyWrap(key, cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	protected := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"A256KW","enc":"A256GCM"}`))
+	aesGCM, err := newGCM(cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := make([]byte, aesGCM.NonceSize())
+	sealed := aesGCM.Seal(nil, iv, []byte("secret"), []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-aesGCM.Overhead()], sealed[len(sealed)-aesGCM.Overhead():]
+
+	token := strings.Join([]string{
+		protected,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, ".")
+
+	if _, err := e.(JWEEncrypter).DecryptJWE(token); err == nil || !strings.Contains(err.Error(), "content encryption key should be 32 bytes") {
+		t.Errorf("DecryptJWE() error = %v, want content encryption key length error", err)
+	}
+}
@@ -0,0 +1,180 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+//Version of the key of services created with NewEncryptionService
+const DefaultKeyVersion uint32 = 1
+
+//Keyring holds all versions of a key, values are encrypted with the current version
+type Keyring struct {
+	Current uint32            `json:"current"`
+	Keys    map[uint32][]byte `json:"keys"`
+	//Version the keys of index tokens, streams, file names, document MACs and field options are derived from, it does not change when the keyring is rotated.
+	//Defaults to the lowest version.
+	Derive uint32 `json:"derive,omitempty"`
+}
+
+//Create encryption service that encrypts with the current key of the keyring and can decrypt text ciphertexts of every version
+func NewKeyringEncryptionService(keyring Keyring) (EncryptionService, error) {
+	if keyring.Current == 0 {
+		return nil, errors.New("key versions should start at 1")
+	}
+
+	keys := make(map[uint32][]byte, len(keyring.Keys))
+	for version, key := range keyring.Keys {
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("key version %d should be 16, 24 or 32 bytes", version)
+		}
+		keys[version] = key
+	}
+
+	current, ok := keys[keyring.Current]
+	if !ok {
+		return nil, fmt.Errorf("keyring does not contain current key version %d", keyring.Current)
+	}
+
+	derive := keyring.DeriveVersion()
+	if _, ok := keys[derive]; !ok {
+		return nil, fmt.Errorf("keyring does not contain key version %d that keys are derived from", derive)
+	}
+
+	return &encryptionService{
+		key:     current,
+		keyring: Keyring{Current: keyring.Current, Keys: keys, Derive: derive},
+	}, nil
+}
+
+//Read keyring from a JSON file written by WriteKeyringFile
+func ReadKeyringFile(path string) (Keyring, error) {
+	var keyring Keyring
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return keyring, fmt.Errorf("failed to read keyring, the following error occured: %s", err)
+	}
+
+	if err := json.Unmarshal(b, &keyring); err != nil {
+		return keyring, fmt.Errorf("failed to parse keyring, the following error occured: %s", err)
+	}
+
+	return keyring, nil
+}
+
+//Write keyring as JSON to a temp file in the same directory, sync it and move it into place, so that a crash or a concurrent writer never leaves a partial keyring
+func WriteKeyringFile(path string, keyring Keyring, overwrite bool) (err error) {
+	b, err := json.MarshalIndent(keyring, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file, the following error occured: %s", err)
+	}
+
+	//Remove the temp file when anything fails
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write keyring, the following error occured: %s", err)
+	}
+
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temporary file, the following error occured: %s", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file, the following error occured: %s", err)
+	}
+
+	return publishFile(tmp.Name(), path, overwrite)
+}
+
+//Get the version keys are derived from, the lowest version unless Derive is set
+func (k Keyring) DeriveVersion() uint32 {
+	if k.Derive != 0 {
+		return k.Derive
+	}
+
+	var lowest uint32
+	for version := range k.Keys {
+		if lowest == 0 || version < lowest {
+			lowest = version
+		}
+	}
+
+	return lowest
+}
+
+//Get the key other keys are derived from, it stays the same when a keyring is rotated so that derived data keeps working
+func (e *encryptionService) derivationKey() []byte {
+	if len(e.keyring.Keys) > 0 {
+		return e.keyring.Keys[e.keyring.Derive]
+	}
+
+	return e.key
+}
+
+//Get the AEADs of all key versions other than the current one, newest first, to open binary ciphertexts that carry no version
+func (e *encryptionService) olderGCMs() ([]cipher.AEAD, error) {
+	versions := make([]uint32, 0, len(e.keyring.Keys))
+	for version := range e.keyring.Keys {
+		if version != e.keyring.Current {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+
+	gcms := make([]cipher.AEAD, 0, len(versions))
+	for _, version := range versions {
+		aesGCM, err := newGCM(e.keyring.Keys[version])
+		if err != nil {
+			return nil, err
+		}
+		gcms = append(gcms, aesGCM)
+	}
+
+	return gcms, nil
+}
+
+//Get the version of the key values are encrypted with, 0 for services without a symmetric key
+func (e *encryptionService) keyVersion() uint32 {
+	if len(e.keyring.Keys) > 0 {
+		return e.keyring.Current
+	}
+	if len(e.key) > 0 {
+		return DefaultKeyVersion
+	}
+
+	return 0
+}
+
+//Get the key with the given version
+func (e *encryptionService) keyForVersion(version uint32) ([]byte, error) {
+	if len(e.keyring.Keys) == 0 {
+		if version == DefaultKeyVersion && len(e.key) > 0 {
+			return e.key, nil
+		}
+	} else if key, ok := e.keyring.Keys[version]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("key version %d is not available", version)
+}
@@ -0,0 +1,239 @@
+package encryption
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_NewKeyringEncryptionService(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		name    string
+		keyring Keyring
+		wantErr bool
+	}{
+		{
+			name:    "valid keyring",
+			keyring: Keyring{Current: 2, Keys: map[uint32][]byte{1: key[:16], 2: key}},
+			wantErr: false,
+		},
+		{
+			name:    "missing current version",
+			keyring: Keyring{Current: 3, Keys: map[uint32][]byte{1: key, 2: key}},
+			wantErr: true,
+		},
+		{
+			name:    "version 0",
+			keyring: Keyring{Current: 0, Keys: map[uint32][]byte{0: key}},
+			wantErr: true,
+		},
+		{
+			name:    "missing derive version",
+			keyring: Keyring{Current: 2, Keys: map[uint32][]byte{1: key, 2: key}, Derive: 3},
+			wantErr: true,
+		},
+		{
+			name:    "invalid key size",
+			keyring: Keyring{Current: 1, Keys: map[uint32][]byte{1: key[:10]}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewKeyringEncryptionService(tt.keyring)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewKeyringEncryptionService() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			//Binary ciphertexts use the current key
+			b, err := e.EncryptStr("value")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewEncryptionService(tt.keyring.Keys[tt.keyring.Current]).DecryptStr(b); err != nil {
+				t.Errorf("expected current key to decrypt binary ciphertext, got error %v", err)
+			}
+		})
+	}
+}
+
+type rotatedModel struct {
+	Id     string `bson:"_id" encrypted:"false"`
+	Name   string `bson:"name" search:"prefix"`
+	Salary int    `bson:"salary" range:"width=1000"`
+	Email  string `bson:"email" encrypted:"true,key=pii,mode=deterministic"`
+}
+
+type rotatedModelEnc struct {
+	Id          string   `bson:"_id" encrypted:"false"`
+	Name        []byte   `bson:"name"`
+	NameSearch  []string `bson:"name_search"`
+	Salary      []byte   `bson:"salary"`
+	SalaryRange string   `bson:"salary_range"`
+	Email       []byte   `bson:"email"`
+	MAC         []byte   `bson:"_mac"`
+}
+
+//Everything written before a rotation can still be searched and decrypted afterwards
+func Test_Keyring_Rotation(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+
+	service := func(keyring Keyring) EncryptionService {
+		e, err := NewKeyringEncryptionService(keyring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err = e.(DocumentAuthenticator).WithDocumentMAC()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return e
+	}
+	before := service(Keyring{Current: 1, Keys: map[uint32][]byte{1: oldKey}})
+	after := service(Keyring{Current: 2, Keys: map[uint32][]byte{1: oldKey, 2: newKey}})
+
+	model := rotatedModel{Id: "1", Name: "alice", Salary: 45250, Email: "alice@example.com"}
+	encrypted, err := before.EncryptToInterface(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := rotatedModelEnc{
+		Id:          encrypted["_id"].(string),
+		Name:        encrypted["name"].([]byte),
+		NameSearch:  encrypted["name_search"].([]string),
+		Salary:      encrypted["salary"].([]byte),
+		SalaryRange: encrypted["salary_range"].(string),
+		Email:       encrypted["email"].([]byte),
+		MAC:         encrypted[DocumentMACField].([]byte),
+	}
+
+	var stream bytes.Buffer
+	w, err := before.(StreamEncrypter).NewEncryptWriter(&stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("stream")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fileName, err := before.(FileEncrypter).EncryptFileName("report.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwe, err := before.(JWEEncrypter).EncryptJWE([]byte("jwe"), JWEAlgA256KW)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Searching with the rotated service finds the old document
+	tokens, err := after.(BlindIndexer).SearchTokens(rotatedModel{}, "name", "ali")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsString(doc.NameSearch, tokens[0]) {
+		t.Errorf("search token %s of rotated keyring not found in %v", tokens[0], doc.NameSearch)
+	}
+	rangeTokens, err := after.(BlindIndexer).RangeTokens(rotatedModel{}, "salary", 45000, 45999)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsString(rangeTokens, doc.SalaryRange) {
+		t.Errorf("range token %s not found in tokens of rotated keyring %v", doc.SalaryRange, rangeTokens)
+	}
+	again, err := after.EncryptToInterface(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again["email"].([]byte), doc.Email) {
+		t.Errorf("deterministic field changed after rotation")
+	}
+
+	//Binary ciphertexts, the MAC, streams, file names and JWE of the old version can be decrypted
+	decrypted, err := after.Decrypt(doc, rotatedModel{})
+	if err != nil {
+		t.Fatalf("Decrypt() after rotation error = %v", err)
+	}
+	if !reflect.DeepEqual(decrypted, &model) {
+		t.Errorf("Decrypt() after rotation = %+v, want %+v", decrypted, model)
+	}
+
+	r, err := after.(StreamEncrypter).NewDecryptReader(&stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(r); err != nil || string(b) != "stream" {
+		t.Errorf("stream after rotation = %q, %v", b, err)
+	}
+
+	if name, err := after.(FileEncrypter).DecryptFileName(fileName); err != nil || name != "report.pdf" {
+		t.Errorf("DecryptFileName() after rotation = %q, %v", name, err)
+	}
+	if b, err := after.(JWEEncrypter).DecryptJWE(jwe); err != nil || string(b) != "jwe" {
+		t.Errorf("DecryptJWE() after rotation = %q, %v", b, err)
+	}
+
+	//New values use the new version
+	b, err := after.EncryptStr("value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := before.DecryptStr(b); err == nil {
+		t.Errorf("value encrypted after rotation could be decrypted with the old version")
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func Test_WriteKeyringFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.keyring.json")
+	keyring := Keyring{Current: 1, Keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}, Derive: 1}
+
+	if err := WriteKeyringFile(path, keyring, false); err != nil {
+		t.Fatalf("WriteKeyringFile() error = %v", err)
+	}
+	if err := WriteKeyringFile(path, keyring, false); err == nil {
+		t.Errorf("WriteKeyringFile() without overwrite should not replace an existing keyring")
+	}
+
+	keyring.Current, keyring.Keys[2] = 2, bytes.Repeat([]byte{2}, 32)
+	if err := WriteKeyringFile(path, keyring, true); err != nil {
+		t.Fatalf("WriteKeyringFile() with overwrite error = %v", err)
+	}
+
+	got, err := ReadKeyringFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, keyring) {
+		t.Errorf("ReadKeyringFile() = %+v, want %+v", got, keyring)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory contains %d files, temp files were left behind", len(entries))
+	}
+}
@@ -1,18 +1,31 @@
 package encryption
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"time"
 )
 
 type encryptionService struct {
-	key []byte
+	key        []byte
+	recipients []recipient
+	identity   identity
+	signingKey ed25519.PrivateKey
+	keyring    Keyring
+	remote     *remoteClient
+	clock      func() time.Time
+	//Key ID of a JWK imported with NewEncryptionServiceFromJWK, empty when the RFC 7638 thumbprint is used
+	jwkID string
+
+	documentMAC bool
 }
 
 //Create encryption service by passing a 32-bit key as parameter
@@ -23,9 +36,50 @@ func NewEncryptionService(key []byte) EncryptionService {
 }
 
 //Helper functions to remove code duplication
-func (e *encryptionService) initGCM() (cipher.AEAD, error) {
+
+//Seals and opens single values, the implementation depends on the kind of service
+type sealer interface {
+	seal(plain []byte) ([]byte, error)
+	open(ciphertext []byte) ([]byte, error)
+}
+
+//Sealer of services with a symmetric key, values are a random nonce followed by the AES-GCM ciphertext
+type gcmSealer struct {
+	aesGCM cipher.AEAD
+	//Older versions of a keyring, binary ciphertexts carry no version so every version is tried
+	older []cipher.AEAD
+}
+
+//Sealer of services created with public keys, values are sealed in envelopes
+type envelopeSealer struct {
+	e *encryptionService
+}
+
+//Get the sealer of the service, services created with public keys or for the encryption server have no single AEAD
+func (e *encryptionService) newSealer() (sealer, error) {
+	if e.remote != nil {
+		return e.remote, nil
+	}
+	if len(e.recipients) > 0 {
+		return envelopeSealer{e: e}, nil
+	}
+
+	aesGCM, err := newGCM(e.key)
+	if err != nil {
+		return nil, err
+	}
+
+	older, err := e.olderGCMs()
+	if err != nil {
+		return nil, err
+	}
+
+	return gcmSealer{aesGCM: aesGCM, older: older}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
 	//Create cipher with given key
-	block, err := aes.NewCipher(e.key)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher, external aes package returned the following error: %s", err)
 	}
@@ -38,18 +92,48 @@ func (e *encryptionService) initGCM() (cipher.AEAD, error) {
 	return aesGCM, nil
 }
 
-func (e *encryptionService) sealWithNonce(aesGCM cipher.AEAD, str string) ([]byte, error) {
+func (g gcmSealer) seal(plain []byte) ([]byte, error) {
 	//Create random nonce so that the same input value changes when it is encrypted
-	nonce := make([]byte, aesGCM.NonceSize())
+	nonce := make([]byte, g.aesGCM.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, err
 	}
 
-	val := aesGCM.Seal(nonce, nonce, []byte(str), nil) //Encrypt using all values
+	val := g.aesGCM.Seal(nonce, nonce, plain, nil) //Encrypt using all values
 
 	return val, nil
 }
 
+func (g gcmSealer) open(val []byte) ([]byte, error) {
+	//Get nonce size
+	nonceSize := g.aesGCM.NonceSize()
+	if len(val) < nonceSize {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	nonce, ciphertext := val[:nonceSize], val[nonceSize:]
+	plainbytes, err := g.aesGCM.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		for _, aesGCM := range g.older {
+			if plainbytes, olderErr := aesGCM.Open(nil, nonce, ciphertext, nil); olderErr == nil {
+				return plainbytes, nil
+			}
+		}
+
+		return nil, err
+	}
+
+	return plainbytes, nil
+}
+
+func (s envelopeSealer) seal(plain []byte) ([]byte, error) {
+	return sealEnvelope(s.e.recipients, plain)
+}
+
+func (s envelopeSealer) open(val []byte) ([]byte, error) {
+	return s.e.openEnvelope(val)
+}
+
 func (e *encryptionService) findFieldTag(object reflect.StructTag, fieldTagNames []string) (string, error) {
 	for i := 0; i < len(fieldTagNames); i++ {
 		field := object.Get(fieldTagNames[i])
@@ -62,16 +146,26 @@ func (e *encryptionService) findFieldTag(object reflect.StructTag, fieldTagNames
 	return "", errors.New("none of the necessary field tags were found in the structure could not map to interface")
 }
 
+//Derive a separate key for the given purpose so that the encryption key itself is never used for HMAC
+func (e *encryptionService) deriveKey(label string) ([]byte, error) {
+	key := e.derivationKey()
+	if len(key) == 0 {
+		return nil, errors.New("operation requires a service created with a symmetric key")
+	}
+
+	return deriveKeyFrom(key, label), nil
+}
+
 //END
 
 //Get encrypted []byte by inputting string
 func (e *encryptionService) EncryptStr(str string) ([]byte, error) {
-	aesGCM, err := e.initGCM()
+	s, err := e.newSealer()
 	if err != nil {
 		return nil, err
 	}
 
-	val, err := e.sealWithNonce(aesGCM, str)
+	val, err := s.seal([]byte(str))
 	if err != nil {
 		return nil, err
 	}
@@ -85,33 +179,54 @@ func (e *encryptionService) EncryptToInterface(eData interface{}) (map[string]in
 	object := reflect.ValueOf(eData)
 	returnObj := map[string]interface{}{}
 
-	aesGCM, err := e.initGCM()
+	s, err := e.newSealer()
 	if err != nil {
 		return nil, err
 	}
 
-	//For each field in object
-	for i := 0; i < object.NumField(); i++ {
-		fieldName, err := e.findFieldTag(object.Type().Field(i).Tag, []string{"bson", "ename"})
+	//For each field in object, the tags were parsed when the type was first used
+	plan := planFor(object.Type())
+	if plan.err != nil {
+		return nil, plan.err
+	}
+	for i := range plan.fields {
+		field := &plan.fields[i]
+		fieldName, err := field.name(interfaceTagNames)
 		if err != nil {
 			return nil, err
 		}
 
-		//Get encrypted tag
-		encrypt := object.Type().Field(i).Tag.Get("encrypted")
+		//Empty values of omitempty fields are left out together with their index tokens
+		if field.options.omitEmpty && object.Field(field.index).IsZero() {
+			continue
+		}
+
 		//If encrypted == false don't encrypt otherwise encrypt
-		if encrypt != "false" {
-			val, err := e.sealWithNonce(aesGCM, Encode(object.Field(i)))
+		if field.encrypted {
+			val, err := e.sealField(s, field, field.encode(object.Field(field.index)), field.aad(object))
 			if err != nil {
 				return nil, err
 			}
 
 			returnObj[fieldName] = val
 		} else {
-			val := fmt.Sprint(object.Field(i))
+			val := fmt.Sprint(object.Field(field.index))
 			returnObj[fieldName] = val
 		}
 
+		//Add blind index tokens if field is searchable
+		if err := e.addSearchTokens(returnObj, fieldName, field, object.Field(field.index)); err != nil {
+			return nil, err
+		}
+
+		//Add range index token if field is range searchable
+		if err := e.addRangeToken(returnObj, fieldName, field, object.Field(field.index)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.addDocumentMAC(returnObj, object, interfaceTagNames); err != nil {
+		return nil, err
 	}
 
 	return returnObj, nil
@@ -123,31 +238,51 @@ func (e *encryptionService) EncryptToJSON(eData interface{}) ([]byte, error) {
 	object := reflect.ValueOf(eData)
 	returnObj := map[string]interface{}{}
 
-	aesGCM, err := e.initGCM()
+	s, err := e.newSealer()
 	if err != nil {
 		return nil, err
 	}
 
 	//For each field in object
-	for i := 0; i < object.NumField(); i++ {
-		fieldName, err := e.findFieldTag(object.Type().Field(i).Tag, []string{"bson", "json"})
+	plan := planFor(object.Type())
+	if plan.err != nil {
+		return nil, plan.err
+	}
+	for i := range plan.fields {
+		field := &plan.fields[i]
+		fieldName, err := field.name(jsonTagNames)
 		if err != nil {
 			return nil, err
 		}
 
-		encrypt := object.Type().Field(i).Tag.Get("encrypted")
+		if field.options.omitEmpty && object.Field(field.index).IsZero() {
+			continue
+		}
 
-		if encrypt != "false" {
-			val, err := e.sealWithNonce(aesGCM, Encode(object.Field(i)))
+		if field.encrypted {
+			val, err := e.sealField(s, field, field.encode(object.Field(field.index)), field.aad(object))
 			if err != nil {
 				return nil, err
 			}
 			returnObj[fieldName] = val
 		} else {
-			val := fmt.Sprint(object.Field(i))
+			val := fmt.Sprint(object.Field(field.index))
 			returnObj[fieldName] = val
 		}
 
+		//Add blind index tokens if field is searchable
+		if err := e.addSearchTokens(returnObj, fieldName, field, object.Field(field.index)); err != nil {
+			return nil, err
+		}
+
+		//Add range index token if field is range searchable
+		if err := e.addRangeToken(returnObj, fieldName, field, object.Field(field.index)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.addDocumentMAC(returnObj, object, jsonTagNames); err != nil {
+		return nil, err
 	}
 
 	jsonBytes, err := json.Marshal(returnObj)
@@ -164,20 +299,71 @@ func (e *encryptionService) Decrypt(encryptedData interface{}, desiredOutput int
 	object := reflect.ValueOf(encryptedData)
 	returnObj := reflect.New(reflect.ValueOf(desiredOutput).Type())
 
-	aesGCM, err := e.initGCM()
+	s, err := e.newSealer()
 	if err != nil {
 		return nil, err
 	}
 
-	//For each field in object
+	var doc *documentMAC
+	var storedMAC []byte
+	if e.documentMAC {
+		doc = &documentMAC{}
+	}
+
+	//For each field in object, out is the matching field in the output since the MAC and index fields have no counterpart
+	plan, outPlan := planFor(object.Type()), planFor(returnObj.Type().Elem())
+	if outPlan.err != nil {
+		return nil, outPlan.err
+	}
+	out := 0
 	for i := 0; i < object.NumField(); i++ {
+		fieldName, _ := plan.fields[i].name(decryptTagNames)
+		if fieldName == DocumentMACField {
+			storedMAC = fieldCiphertext(object.Field(i))
+			continue
+		}
+
+		//Index fields have no counterpart either, they are only read for the document MAC
+		if owner, ok := outPlan.indexNames[fieldName]; ok {
+			//Omitted empty values have no index tokens either, like in EncryptToInterface and EncryptToJSON
+			omitted := outPlan.fields[owner].options.omitEmpty && isEmptyIndex(object.Field(i))
+			if doc != nil && !omitted {
+				doc.add(fieldName, false, indexFieldValue(object.Field(i)))
+			}
+			continue
+		}
+
 		//Get encrypted tag
-		encrypted := reflect.Indirect(returnObj).Type().Field(i).Tag.Get("encrypted")
+		outField := &outPlan.fields[out]
+		encrypted := outField.encrypted
+
+		//Omitted empty values stay empty and were not part of the document MAC
+		if outField.options.omitEmpty && encrypted && fieldCiphertext(object.Field(i)) == nil {
+			out++
+			continue
+		}
+
+		if doc != nil {
+			if fieldName == "" {
+				return nil, fmt.Errorf("field %s needs a bson, ename or json tag to verify the document MAC", object.Type().Field(i).Name)
+			}
+
+			if encrypted {
+				doc.add(fieldName, true, textPayload(fieldCiphertext(object.Field(i))))
+			} else {
+				doc.add(fieldName, false, []byte(fmt.Sprint(object.Field(i))))
+			}
+		}
 
 		var decryptedStr string
 		//If encrypted == false don't decrypt, if value is nil don't decrypt otherwise decrypt
-		if encrypted != "false" && object.Field(i).Bytes() != nil {
-			decryptedStr, err = e.getPlainText(object.Field(i).Bytes(), aesGCM)
+		if encrypted && fieldCiphertext(object.Field(i)) != nil {
+			aad, err := outField.encryptedAAD(plan, object)
+			if err != nil {
+				return nil, err
+			}
+
+			decryptedStr, err = e.openField(s, outField, fieldCiphertext(object.Field(i)), aad)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get text out of encrypted value, the following error occured: %s", err)
 			}
@@ -186,15 +372,22 @@ func (e *encryptionService) Decrypt(encryptedData interface{}, desiredOutput int
 		}
 
 		//Convert string to desired type
-		field := reflect.Indirect(returnObj).Field(i)
+		field := reflect.Indirect(returnObj).Field(out)
 		if field.IsValid() {
-			val, err := Decode(decryptedStr, field)
+			val, err := decodeType(outField.typeName, decryptedStr)
 			if err != nil {
 				return nil, err
 			}
 
 			field.Set(reflect.ValueOf(val))
 		}
+		out++
+	}
+
+	if doc != nil {
+		if err := e.verifyDocumentMAC(doc, storedMAC); err != nil {
+			return nil, err
+		}
 	}
 
 	return returnObj.Interface(), nil
@@ -203,13 +396,13 @@ func (e *encryptionService) Decrypt(encryptedData interface{}, desiredOutput int
 //Decrypt encrypted []byte of a string
 func (e *encryptionService) DecryptStr(b []byte) (string, error) {
 	//Create cipher using given key
-	aesGCM, err := e.initGCM()
+	s, err := e.newSealer()
 	if err != nil {
 		return "", err
 	}
 
 	//Decrypt string
-	decryptedStr, err := e.getPlainText(b, aesGCM)
+	decryptedStr, err := e.getPlainText(b, s)
 	if err != nil {
 		return "", fmt.Errorf("failed to decrypt string, the following error occured: %s", err)
 	}
@@ -217,15 +410,9 @@ func (e *encryptionService) DecryptStr(b []byte) (string, error) {
 	return decryptedStr, nil
 }
 
-//Get decrypted []byte of encrypted value
-func (e encryptionService) getPlainText(val []byte, aesGCM cipher.AEAD) (string, error) {
-	//Get nonce size
-	nonceSize := aesGCM.NonceSize()
-
-	//Remove nonce from bytes
-	nonce, ciphertext := val[:nonceSize], val[nonceSize:]
-	//Get decrypted bytes
-	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
+//Get decrypted string of encrypted value
+func (e encryptionService) getPlainText(val []byte, s sealer) (string, error) {
+	plaintext, err := e.getPlainBytes(val, s)
 	if err != nil {
 		return "", err
 	}
@@ -236,12 +423,12 @@ func (e encryptionService) getPlainText(val []byte, aesGCM cipher.AEAD) (string,
 //Encrypt []byte
 func (e *encryptionService) EncryptByt(b []byte) ([]byte, error) {
 	//Create new cipher using given key
-	aesGCM, err := e.initGCM()
+	s, err := e.newSealer()
 	if err != nil {
 		return nil, err
 	}
 
-	val, err := e.sealWithNonce(aesGCM, string(b))
+	val, err := s.seal(b)
 	if err != nil {
 		return nil, err
 	}
@@ -252,13 +439,13 @@ func (e *encryptionService) EncryptByt(b []byte) ([]byte, error) {
 //Decrypt byte
 func (e *encryptionService) DecryptByt(b []byte) ([]byte, error) {
 	//Create new cipher using given key
-	aesGCM, err := e.initGCM()
+	s, err := e.newSealer()
 	if err != nil {
 		return nil, err
 	}
 
 	//Decrypt bytes
-	decryptedBytes, err := e.getPlainBytes(b, aesGCM)
+	decryptedBytes, err := e.getPlainBytes(b, s)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt string, the following error occured: %s", err)
 	}
@@ -266,16 +453,22 @@ func (e *encryptionService) DecryptByt(b []byte) ([]byte, error) {
 	return decryptedBytes, nil
 }
 
-//Get decrypted bytes from encrypted []byte
-func (e encryptionService) getPlainBytes(val []byte, aesGCM cipher.AEAD) ([]byte, error) {
-	//Get nonce size
-	nonceSize := aesGCM.NonceSize()
+//Get decrypted bytes from encrypted []byte, text ciphertexts are accepted as well
+func (e encryptionService) getPlainBytes(val []byte, s sealer) ([]byte, error) {
+	//The encryption server accepts both forms itself
+	if bytes.HasPrefix(val, []byte(TextPrefix)) && e.remote == nil {
+		plainbytes, err := e.openText(val)
+		if err == nil {
+			return plainbytes, nil
+		}
+
+		//Binary ciphertext can start with the prefix by chance
+		if plainbytes, binErr := s.open(val); binErr == nil {
+			return plainbytes, nil
+		}
 
-	nonce, ciphertext := val[:nonceSize], val[nonceSize:]
-	plainbytes, err := aesGCM.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
 		return nil, err
 	}
 
-	return plainbytes, nil
+	return s.open(val)
 }
@@ -1,6 +1,8 @@
 package encryption
 
 import (
+	"crypto/ecdh"
+	"crypto/rand"
 	"fmt"
 	"reflect"
 	"testing"
@@ -181,3 +183,51 @@ func Test_encryptionService_EncryptBytes(t *testing.T) {
 		})
 	}
 }
+
+func Test_encryptionService_newSealer(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := NewPrivateKeyEncryptionService(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		e       EncryptionService
+		want    sealer
+		wantErr bool
+	}{
+		{name: "symmetric key", e: NewEncryptionService([]byte("0123456789abcdef0123456789abcdef")), want: gcmSealer{}},
+		{name: "public key", e: publicKey, want: envelopeSealer{}},
+		{name: "invalid key", e: NewEncryptionService([]byte("short")), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := tt.e.(*encryptionService).newSealer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newSealer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if s != nil {
+					t.Errorf("newSealer() = %v, want nil sealer with error", s)
+				}
+				return
+			}
+			if reflect.TypeOf(s) != reflect.TypeOf(tt.want) {
+				t.Errorf("newSealer() = %T, want %T", s, tt.want)
+			}
+
+			sealed, err := s.seal([]byte("value"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			plain, err := s.open(sealed)
+			if err != nil || string(plain) != "value" {
+				t.Errorf("open() = %q, %v, want value", plain, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Decides which values of a map document are encrypted, patterns are dot separated paths like address.street or users.*.ssn
+//where * matches one key or slice index and ** matches any number of them, so **.ssn matches ssn at any depth
+type FieldPolicy struct {
+	//Values to encrypt, everything that is not denied is encrypted when empty
+	Allow []string
+	//Values that are never encrypted, deny wins over allow
+	Deny []string
+}
+
+//Types that can be encrypted in map documents, the name is stored with the value so that it is restored on decryption
+var mapValueTypes = map[string]reflect.Type{}
+
+func init() {
+	for _, v := range []interface{}{
+		"", false, int(0), int8(0), int16(0), int32(0), int64(0), uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0), time.Time{}, []byte{}, []string{},
+	} {
+		mapValueTypes[reflect.TypeOf(v).String()] = reflect.TypeOf(v)
+	}
+}
+
+type mapPolicy struct {
+	allow [][]string
+	deny  [][]string
+}
+
+//Encrypt values of nested maps and slices selected by the policy, the structure and the values that are not encrypted are kept as they are
+func (e *encryptionService) EncryptMap(doc map[string]interface{}, policy FieldPolicy) (map[string]interface{}, error) {
+	p, err := parseFieldPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := e.newSealer()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := walkMap(reflect.ValueOf(doc), nil, func(path []string, v reflect.Value) (interface{}, error) {
+		if !p.selects(path) || !v.IsValid() {
+			return valueInterface(v), nil
+		}
+
+		plain, err := encodeMapValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s, the following error occured: %s", strings.Join(path, "."), err)
+		}
+
+		return s.seal([]byte(plain))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.(map[string]interface{}), nil
+}
+
+//Decrypt values of map document created by EncryptMap, the policy should be the one used for encryption
+func (e *encryptionService) DecryptMap(doc map[string]interface{}, policy FieldPolicy) (map[string]interface{}, error) {
+	p, err := parseFieldPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := e.newSealer()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := walkMap(reflect.ValueOf(doc), nil, func(path []string, v reflect.Value) (interface{}, error) {
+		if !p.selects(path) || !v.IsValid() {
+			return valueInterface(v), nil
+		}
+
+		if v.Kind() != reflect.String && v.Type() != reflect.TypeOf([]byte{}) {
+			return nil, fmt.Errorf("value of %s is a %s and not an encrypted value", strings.Join(path, "."), v.Type())
+		}
+
+		plain, err := e.getPlainText(fieldCiphertext(v), s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s, the following error occured: %s", strings.Join(path, "."), err)
+		}
+
+		return decodeMapValue(plain)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.(map[string]interface{}), nil
+}
+
+//Copy maps with string keys and slices of interfaces, which also covers named types like bson.M and bson.A, and pass everything else to leaf
+func walkMap(v reflect.Value, path []string, leaf func(path []string, v reflect.Value) (interface{}, error)) (interface{}, error) {
+	//Values of interfaces are wrapped, nil stays invalid
+	for v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch {
+	case isMapContainer(v):
+		if v.IsNil() {
+			return v.Interface(), nil
+		}
+
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			val, err := walkMap(iter.Value(), append(append([]string{}, path...), iter.Key().String()), leaf)
+			if err != nil {
+				return nil, err
+			}
+
+			out.SetMapIndex(iter.Key(), mapElem(val, v.Type().Elem()))
+		}
+
+		return out.Interface(), nil
+	case isSliceContainer(v):
+		if v.IsNil() {
+			return v.Interface(), nil
+		}
+
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			val, err := walkMap(v.Index(i), append(append([]string{}, path...), strconv.Itoa(i)), leaf)
+			if err != nil {
+				return nil, err
+			}
+
+			out.Index(i).Set(mapElem(val, v.Type().Elem()))
+		}
+
+		return out.Interface(), nil
+	default:
+		return leaf(path, v)
+	}
+}
+
+func isMapContainer(v reflect.Value) bool {
+	return v.IsValid() && v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String && v.Type().Elem().Kind() == reflect.Interface
+}
+
+func isSliceContainer(v reflect.Value) bool {
+	return v.IsValid() && v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Interface
+}
+
+//Get value that can be stored in a container with the given element type, nil needs a typed zero value
+func mapElem(val interface{}, elemType reflect.Type) reflect.Value {
+	if val == nil {
+		return reflect.Zero(elemType)
+	}
+
+	return reflect.ValueOf(val)
+}
+
+func valueInterface(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	return v.Interface()
+}
+
+//Encode value with its type name so that DecryptMap can restore the native type
+func encodeMapValue(v reflect.Value) (string, error) {
+	name := v.Type().String()
+	if _, ok := mapValueTypes[name]; !ok {
+		return "", fmt.Errorf("type %s is not supported", name)
+	}
+
+	val, err := encodeValue(v)
+	if err != nil {
+		return "", err
+	}
+
+	return name + ":" + val, nil
+}
+
+func decodeMapValue(s string) (interface{}, error) {
+	name, val, found := strings.Cut(s, ":")
+	typ, ok := mapValueTypes[name]
+	if !found || !ok {
+		return nil, errors.New("decrypted value has no supported type")
+	}
+
+	return decodeValue(val, typ)
+}
+
+func parseFieldPolicy(policy FieldPolicy) (*mapPolicy, error) {
+	p := &mapPolicy{}
+
+	for _, list := range []struct {
+		patterns []string
+		out      *[][]string
+	}{{policy.Allow, &p.allow}, {policy.Deny, &p.deny}} {
+		for _, pattern := range list.patterns {
+			segments := strings.Split(pattern, ".")
+			for _, seg := range segments {
+				if seg == "" {
+					return nil, fmt.Errorf("field pattern %q has an empty segment", pattern)
+				}
+				if _, err := path.Match(seg, ""); err != nil {
+					return nil, fmt.Errorf("field pattern %q is not valid, the following error occured: %s", pattern, err)
+				}
+			}
+			*list.out = append(*list.out, segments)
+		}
+	}
+
+	return p, nil
+}
+
+//Check whether the value at path is encrypted, patterns that match a map or slice select everything in it
+func (p *mapPolicy) selects(path []string) bool {
+	if matchFieldPatterns(p.deny, path) {
+		return false
+	}
+
+	return len(p.allow) == 0 || matchFieldPatterns(p.allow, path)
+}
+
+func matchFieldPatterns(patterns [][]string, path []string) bool {
+	for _, pattern := range patterns {
+		for end := 1; end <= len(path); end++ {
+			if matchFieldPattern(pattern, path[:end]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchFieldPattern(pattern []string, p []string) bool {
+	if len(pattern) == 0 {
+		return len(p) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(p); i++ {
+			if matchFieldPattern(pattern[1:], p[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(p) == 0 {
+		return false
+	}
+
+	//Patterns were validated when the policy was parsed
+	if ok, _ := path.Match(pattern[0], p[0]); !ok {
+		return false
+	}
+
+	return matchFieldPattern(pattern[1:], p[1:])
+}
@@ -0,0 +1,247 @@
+package encryption
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+//Named map and slice types like bson.M and bson.A
+type testM map[string]interface{}
+type testA []interface{}
+
+func testMapDocument() map[string]interface{} {
+	return map[string]interface{}{
+		"name":    "alice",
+		"age":     int32(41),
+		"balance": 1250.75,
+		"active":  true,
+		"created": time.Date(2024, 3, 1, 12, 30, 0, 5, time.UTC),
+		"avatar":  []byte{0, 1, 2, 255},
+		"tags":    []string{"a", "b"},
+		"manager": nil,
+		"address": testM{
+			"street": "Main Street 1",
+			"zip":    uint16(1234),
+		},
+		"accounts": testA{
+			map[string]interface{}{"iban": "NL91ABNA0417164300", "primary": true},
+			map[string]interface{}{"iban": "DE89370400440532013000", "primary": false},
+		},
+	}
+}
+
+func Test_encryptionService_EncryptMap(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		name          string
+		policy        FieldPolicy
+		wantEncrypted []string
+		wantPlain     []string
+		wantErr       bool
+	}{
+		{
+			name:          "everything",
+			policy:        FieldPolicy{},
+			wantEncrypted: []string{"name", "age", "balance", "active", "created", "avatar", "tags", "address.street", "address.zip", "accounts.0.iban", "accounts.1.primary"},
+			wantPlain:     []string{"manager"},
+		},
+		{
+			name:          "allow list",
+			policy:        FieldPolicy{Allow: []string{"name", "address.street"}},
+			wantEncrypted: []string{"name", "address.street"},
+			wantPlain:     []string{"age", "address.zip", "accounts.0.iban"},
+		},
+		{
+			name:          "deny list",
+			policy:        FieldPolicy{Deny: []string{"name", "accounts.*.primary"}},
+			wantEncrypted: []string{"age", "address.zip", "accounts.0.iban"},
+			wantPlain:     []string{"name", "accounts.0.primary", "accounts.1.primary"},
+		},
+		{
+			name:          "whole sub document",
+			policy:        FieldPolicy{Allow: []string{"address"}},
+			wantEncrypted: []string{"address.street", "address.zip"},
+			wantPlain:     []string{"name", "accounts.0.iban"},
+		},
+		{
+			name:          "path patterns",
+			policy:        FieldPolicy{Allow: []string{"**.iban", "tag?"}},
+			wantEncrypted: []string{"accounts.0.iban", "accounts.1.iban", "tags"},
+			wantPlain:     []string{"name", "accounts.0.primary"},
+		},
+		{
+			name:          "deny wins over allow",
+			policy:        FieldPolicy{Allow: []string{"accounts"}, Deny: []string{"accounts.1"}},
+			wantEncrypted: []string{"accounts.0.iban", "accounts.0.primary"},
+			wantPlain:     []string{"accounts.1.iban", "accounts.1.primary"},
+		},
+		{
+			name:    "empty segment",
+			policy:  FieldPolicy{Allow: []string{"address..street"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern",
+			policy:  FieldPolicy{Deny: []string{"[a"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEncryptionService(key)
+			doc := testMapDocument()
+
+			got, err := e.(DocumentEncrypter).EncryptMap(doc, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncryptMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for _, p := range tt.wantEncrypted {
+				if _, ok := mapPath(got, p).([]byte); !ok {
+					t.Errorf("EncryptMap() %s = %#v, want encrypted value", p, mapPath(got, p))
+				}
+			}
+			for _, p := range tt.wantPlain {
+				if !reflect.DeepEqual(mapPath(got, p), mapPath(doc, p)) {
+					t.Errorf("EncryptMap() %s = %#v, want %#v", p, mapPath(got, p), mapPath(doc, p))
+				}
+			}
+
+			//Named container types are kept
+			if _, ok := got["address"].(testM); !ok {
+				t.Errorf("EncryptMap() address has type %T, want testM", got["address"])
+			}
+			if _, ok := got["accounts"].(testA); !ok {
+				t.Errorf("EncryptMap() accounts has type %T, want testA", got["accounts"])
+			}
+
+			decrypted, err := e.(DocumentEncrypter).DecryptMap(got, tt.policy)
+			if err != nil {
+				t.Fatalf("DecryptMap() error = %v", err)
+			}
+			if !reflect.DeepEqual(decrypted, doc) {
+				t.Errorf("DecryptMap() = %#v, want %#v", decrypted, doc)
+			}
+		})
+	}
+}
+
+func Test_encryptionService_DecryptMap(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	e := NewEncryptionService(key)
+
+	encrypted, err := e.(DocumentEncrypter).EncryptMap(testMapDocument(), FieldPolicy{Allow: []string{"name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		service EncryptionService
+		policy  FieldPolicy
+		wantErr bool
+	}{
+		{
+			name:    "same policy",
+			service: e,
+			policy:  FieldPolicy{Allow: []string{"name"}},
+		},
+		{
+			name:    "plaintext value selected",
+			service: e,
+			policy:  FieldPolicy{Allow: []string{"name", "age"}},
+			wantErr: true,
+		},
+		{
+			name:    "wrong key",
+			service: NewEncryptionService([]byte("fedcba9876543210fedcba9876543210")),
+			policy:  FieldPolicy{Allow: []string{"name"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.service.(DocumentEncrypter).DecryptMap(encrypted, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecryptMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got["name"] != "alice" {
+				t.Errorf("DecryptMap() name = %v, want alice", got["name"])
+			}
+		})
+	}
+}
+
+func Test_encryptionService_EncryptMap_UnsupportedType(t *testing.T) {
+	e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+	doc := map[string]interface{}{"ids": []int{1, 2}, "name": "alice"}
+
+	if _, err := e.(DocumentEncrypter).EncryptMap(doc, FieldPolicy{}); err == nil {
+		t.Errorf("EncryptMap() of []int should fail")
+	}
+
+	//Unsupported types are fine as long as they are not encrypted
+	got, err := e.(DocumentEncrypter).EncryptMap(doc, FieldPolicy{Deny: []string{"ids"}})
+	if err != nil {
+		t.Fatalf("EncryptMap() error = %v", err)
+	}
+	if !reflect.DeepEqual(got["ids"], []int{1, 2}) {
+		t.Errorf("EncryptMap() ids = %v, want [1 2]", got["ids"])
+	}
+}
+
+func Test_matchFieldPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    []string
+		want    bool
+	}{
+		{pattern: "a.b", path: []string{"a", "b"}, want: true},
+		{pattern: "a.b", path: []string{"a", "c"}, want: false},
+		{pattern: "a.*", path: []string{"a", "0"}, want: true},
+		{pattern: "a.*", path: []string{"a", "0", "b"}, want: false},
+		{pattern: "**.b", path: []string{"b"}, want: true},
+		{pattern: "**.b", path: []string{"a", "0", "b"}, want: true},
+		{pattern: "a.**", path: []string{"a", "x", "y"}, want: true},
+		{pattern: "a.**.c", path: []string{"a", "c"}, want: true},
+		{pattern: "a.**.c", path: []string{"a", "b"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			p, err := parseFieldPolicy(FieldPolicy{Allow: []string{tt.pattern}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := matchFieldPattern(p.allow[0], tt.path); got != tt.want {
+				t.Errorf("matchFieldPattern(%s, %v) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+//Get value at dot separated path of nested maps and slices
+func mapPath(doc map[string]interface{}, p string) interface{} {
+	var cur interface{} = doc
+	for _, seg := range strings.Split(p, ".") {
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			cur = c[seg]
+		case testM:
+			cur = c[seg]
+		case testA:
+			i, _ := strconv.Atoi(seg)
+			cur = c[i]
+		}
+	}
+
+	return cur
+}
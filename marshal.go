@@ -0,0 +1,109 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//Text printed instead of the plaintext of Encrypted values
+const Redacted = "[REDACTED]"
+
+//Implement json.Marshaler, the value is encrypted with the default service to a text ciphertext string, NULL to null
+func (e Encrypted[T]) MarshalJSON() ([]byte, error) {
+	if !e.Valid {
+		return []byte("null"), nil
+	}
+
+	text, err := e.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(string(text))
+}
+
+//Implement json.Unmarshaler, text ciphertexts and standard base64 of binary ciphertexts are accepted
+func (e *Encrypted[T]) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*e = Encrypted[T]{}
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(b, &text); err != nil {
+		return errors.New("encrypted value should be a string or null")
+	}
+
+	return e.UnmarshalText([]byte(text))
+}
+
+//Implement encoding.TextMarshaler, the value is encrypted with the default service to a text ciphertext
+func (e Encrypted[T]) MarshalText() ([]byte, error) {
+	if !e.Valid {
+		return nil, nil
+	}
+
+	service, err := DefaultService()
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := e.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	textService, ok := service.(TextEncrypter)
+	if !ok {
+		return nil, errors.New("default encryption service does not implement TextEncrypter")
+	}
+
+	text, err := textService.EncryptStrText(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(text), nil
+}
+
+//Implement encoding.TextUnmarshaler, empty text is NULL
+func (e *Encrypted[T]) UnmarshalText(text []byte) error {
+	*e = Encrypted[T]{}
+	if len(text) == 0 {
+		return nil
+	}
+
+	ciphertext := text
+	if !strings.HasPrefix(string(text), TextPrefix) {
+		var err error
+		if ciphertext, err = base64.StdEncoding.DecodeString(string(text)); err != nil {
+			return errors.New("encrypted value should be a text ciphertext or standard base64")
+		}
+	}
+
+	service, err := DefaultService()
+	if err != nil {
+		return err
+	}
+
+	plain, err := service.DecryptByt(ciphertext)
+	if err != nil {
+		return err
+	}
+
+	return e.decode(plain)
+}
+
+//Implement fmt.Formatter so that printing the value with any verb never shows the plaintext
+func (e Encrypted[T]) Format(f fmt.State, verb rune) {
+	if !e.Valid {
+		io.WriteString(f, "<nil>")
+		return
+	}
+
+	io.WriteString(f, Redacted)
+}
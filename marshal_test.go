@@ -0,0 +1,139 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type encryptedModel struct {
+	Name    string              `json:"name"`
+	SSN     Encrypted[string]   `json:"ssn"`
+	Age     Encrypted[int]      `json:"age"`
+	Tags    Encrypted[[]string] `json:"tags"`
+	Manager EncryptedString     `json:"manager"`
+}
+
+func Test_Encrypted_MarshalJSON(t *testing.T) {
+	setTestDefaultService(t, NewEncryptionService([]byte("0123456789abcdef0123456789abcdef")))
+
+	in := encryptedModel{
+		Name: "alice",
+		SSN:  NewEncrypted("078-05-1120"),
+		Age:  NewEncrypted(41),
+		Tags: NewEncrypted([]string{"a", "b"}),
+	}
+
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Contains(string(b), "078-05-1120") {
+		t.Errorf("json.Marshal() = %s, contains plaintext", b)
+	}
+	if !strings.Contains(string(b), `"ssn":"globe:v1:`) || !strings.Contains(string(b), `"manager":null`) {
+		t.Errorf("json.Marshal() = %s, want text ciphertexts and null", b)
+	}
+
+	var out encryptedModel
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if out.Name != in.Name || out.SSN != in.SSN || out.Age != in.Age || out.Manager.Valid {
+		t.Errorf("json.Unmarshal() = %+v, want %+v", out, in)
+	}
+	if strings.Join(out.Tags.V, ",") != "a,b" {
+		t.Errorf("json.Unmarshal() tags = %v, want [a b]", out.Tags.V)
+	}
+}
+
+func Test_Encrypted_UnmarshalJSON(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	setTestDefaultService(t, NewEncryptionService(key))
+
+	binary, _ := NewEncryptionService(key).EncryptStr("42")
+	otherKey, _ := NewEncryptionService([]byte("fedcba9876543210fedcba9876543210")).(TextEncrypter).EncryptStrText("42")
+
+	tests := []struct {
+		name    string
+		json    string
+		want    Encrypted[int]
+		wantErr bool
+	}{
+		{name: "null", json: `null`, want: Encrypted[int]{}},
+		{name: "empty string", json: `""`, want: Encrypted[int]{}},
+		{name: "base64 binary ciphertext", json: `"` + base64.StdEncoding.EncodeToString(binary) + `"`, want: NewEncrypted(42)},
+		{name: "other key", json: `"` + otherKey + `"`, wantErr: true},
+		{name: "plaintext number", json: `42`, wantErr: true},
+		{name: "plaintext string", json: `"42!"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewEncrypted(7)
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("json.Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("json.Unmarshal() = %+v, want %+v", got.V, tt.want.V)
+			}
+		})
+	}
+}
+
+func Test_Encrypted_MarshalText(t *testing.T) {
+	setTestDefaultService(t, NewEncryptionService([]byte("0123456789abcdef0123456789abcdef")))
+
+	//Text marshaling makes Encrypted usable as map key
+	in := map[EncryptedString]int{NewEncrypted("alice"): 1}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Contains(string(b), "alice") {
+		t.Errorf("json.Marshal() = %s, contains plaintext", b)
+	}
+
+	var out map[EncryptedString]int
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if out[NewEncrypted("alice")] != 1 {
+		t.Errorf("json.Unmarshal() = %v, want alice key", out)
+	}
+
+	SetDefaultService(nil)
+	if _, err := NewEncrypted("alice").MarshalText(); err == nil {
+		t.Errorf("Encrypted.MarshalText() without default service should fail")
+	}
+
+	//Services implemented outside of this package may only have the EncryptionService functions
+	SetDefaultService(struct{ EncryptionService }{NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))})
+	if _, err := NewEncrypted("alice").MarshalText(); err == nil || !strings.Contains(err.Error(), "does not implement TextEncrypter") {
+		t.Errorf("Encrypted.MarshalText() error = %v, want TextEncrypter error", err)
+	}
+}
+
+func Test_Encrypted_Format(t *testing.T) {
+	v := NewEncrypted("078-05-1120")
+	model := encryptedModel{SSN: v}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%d", "%10s"} {
+		t.Run(format, func(t *testing.T) {
+			for _, got := range []string{fmt.Sprintf(format, v), fmt.Sprintf(format, &v), fmt.Sprintf(format, model)} {
+				if strings.Contains(got, "078-05-1120") || strings.Contains(got, fmt.Sprintf("%x", "078-05-1120")) {
+					t.Errorf("fmt.Sprintf(%q) = %s, leaks plaintext", format, got)
+				}
+			}
+		})
+	}
+
+	if got := fmt.Sprint(v); got != Redacted {
+		t.Errorf("fmt.Sprint() = %s, want %s", got, Redacted)
+	}
+	if got := fmt.Sprint(EncryptedString{}); got != "<nil>" {
+		t.Errorf("fmt.Sprint() of NULL = %s, want <nil>", got)
+	}
+}
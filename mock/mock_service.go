@@ -5,8 +5,13 @@
 package mock_encryption
 
 import (
+	ed25519 "crypto/ed25519"
+	io "io"
+	fs "io/fs"
 	reflect "reflect"
+	time "time"
 
+	encryption "github.com/globe-protocol/encryption"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -137,3 +142,807 @@ func (mr *MockEncryptionServiceMockRecorder) EncryptToJSON(eData interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptToJSON", reflect.TypeOf((*MockEncryptionService)(nil).EncryptToJSON), eData)
 }
+
+// MockModelRegistry is a mock of ModelRegistry interface.
+type MockModelRegistry struct {
+	ctrl     *gomock.Controller
+	recorder *MockModelRegistryMockRecorder
+}
+
+// MockModelRegistryMockRecorder is the mock recorder for MockModelRegistry.
+type MockModelRegistryMockRecorder struct {
+	mock *MockModelRegistry
+}
+
+// NewMockModelRegistry creates a new mock instance.
+func NewMockModelRegistry(ctrl *gomock.Controller) *MockModelRegistry {
+	mock := &MockModelRegistry{ctrl: ctrl}
+	mock.recorder = &MockModelRegistryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModelRegistry) EXPECT() *MockModelRegistryMockRecorder {
+	return m.recorder
+}
+
+// MustRegister mocks base method.
+func (m *MockModelRegistry) MustRegister(models ...interface{}) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range models {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "MustRegister", varargs...)
+}
+
+// MustRegister indicates an expected call of MustRegister.
+func (mr *MockModelRegistryMockRecorder) MustRegister(models ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MustRegister", reflect.TypeOf((*MockModelRegistry)(nil).MustRegister), models...)
+}
+
+// Register mocks base method.
+func (m *MockModelRegistry) Register(models ...interface{}) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range models {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Register", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockModelRegistryMockRecorder) Register(models ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockModelRegistry)(nil).Register), models...)
+}
+
+// MockTextEncrypter is a mock of TextEncrypter interface.
+type MockTextEncrypter struct {
+	ctrl     *gomock.Controller
+	recorder *MockTextEncrypterMockRecorder
+}
+
+// MockTextEncrypterMockRecorder is the mock recorder for MockTextEncrypter.
+type MockTextEncrypterMockRecorder struct {
+	mock *MockTextEncrypter
+}
+
+// NewMockTextEncrypter creates a new mock instance.
+func NewMockTextEncrypter(ctrl *gomock.Controller) *MockTextEncrypter {
+	mock := &MockTextEncrypter{ctrl: ctrl}
+	mock.recorder = &MockTextEncrypterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTextEncrypter) EXPECT() *MockTextEncrypterMockRecorder {
+	return m.recorder
+}
+
+// DecryptStrText mocks base method.
+func (m *MockTextEncrypter) DecryptStrText(text string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptStrText", text)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptStrText indicates an expected call of DecryptStrText.
+func (mr *MockTextEncrypterMockRecorder) DecryptStrText(text interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptStrText", reflect.TypeOf((*MockTextEncrypter)(nil).DecryptStrText), text)
+}
+
+// EncryptStrText mocks base method.
+func (m *MockTextEncrypter) EncryptStrText(str string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncryptStrText", str)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncryptStrText indicates an expected call of EncryptStrText.
+func (mr *MockTextEncrypterMockRecorder) EncryptStrText(str interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptStrText", reflect.TypeOf((*MockTextEncrypter)(nil).EncryptStrText), str)
+}
+
+// MockStreamEncrypter is a mock of StreamEncrypter interface.
+type MockStreamEncrypter struct {
+	ctrl     *gomock.Controller
+	recorder *MockStreamEncrypterMockRecorder
+}
+
+// MockStreamEncrypterMockRecorder is the mock recorder for MockStreamEncrypter.
+type MockStreamEncrypterMockRecorder struct {
+	mock *MockStreamEncrypter
+}
+
+// NewMockStreamEncrypter creates a new mock instance.
+func NewMockStreamEncrypter(ctrl *gomock.Controller) *MockStreamEncrypter {
+	mock := &MockStreamEncrypter{ctrl: ctrl}
+	mock.recorder = &MockStreamEncrypterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStreamEncrypter) EXPECT() *MockStreamEncrypterMockRecorder {
+	return m.recorder
+}
+
+// NewDecryptReader mocks base method.
+func (m *MockStreamEncrypter) NewDecryptReader(r io.Reader) (io.Reader, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewDecryptReader", r)
+	ret0, _ := ret[0].(io.Reader)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewDecryptReader indicates an expected call of NewDecryptReader.
+func (mr *MockStreamEncrypterMockRecorder) NewDecryptReader(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewDecryptReader", reflect.TypeOf((*MockStreamEncrypter)(nil).NewDecryptReader), r)
+}
+
+// NewEncryptWriter mocks base method.
+func (m *MockStreamEncrypter) NewEncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewEncryptWriter", w)
+	ret0, _ := ret[0].(io.WriteCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewEncryptWriter indicates an expected call of NewEncryptWriter.
+func (mr *MockStreamEncrypterMockRecorder) NewEncryptWriter(w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewEncryptWriter", reflect.TypeOf((*MockStreamEncrypter)(nil).NewEncryptWriter), w)
+}
+
+// MockFileEncrypter is a mock of FileEncrypter interface.
+type MockFileEncrypter struct {
+	ctrl     *gomock.Controller
+	recorder *MockFileEncrypterMockRecorder
+}
+
+// MockFileEncrypterMockRecorder is the mock recorder for MockFileEncrypter.
+type MockFileEncrypterMockRecorder struct {
+	mock *MockFileEncrypter
+}
+
+// NewMockFileEncrypter creates a new mock instance.
+func NewMockFileEncrypter(ctrl *gomock.Controller) *MockFileEncrypter {
+	mock := &MockFileEncrypter{ctrl: ctrl}
+	mock.recorder = &MockFileEncrypterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFileEncrypter) EXPECT() *MockFileEncrypterMockRecorder {
+	return m.recorder
+}
+
+// DecryptFile mocks base method.
+func (m *MockFileEncrypter) DecryptFile(src, dst string, opts ...encryption.FileOption) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{src, dst}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DecryptFile", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecryptFile indicates an expected call of DecryptFile.
+func (mr *MockFileEncrypterMockRecorder) DecryptFile(src, dst interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{src, dst}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptFile", reflect.TypeOf((*MockFileEncrypter)(nil).DecryptFile), varargs...)
+}
+
+// DecryptFileName mocks base method.
+func (m *MockFileEncrypter) DecryptFileName(name string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptFileName", name)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptFileName indicates an expected call of DecryptFileName.
+func (mr *MockFileEncrypterMockRecorder) DecryptFileName(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptFileName", reflect.TypeOf((*MockFileEncrypter)(nil).DecryptFileName), name)
+}
+
+// EncryptFile mocks base method.
+func (m *MockFileEncrypter) EncryptFile(src, dst string, opts ...encryption.FileOption) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{src, dst}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "EncryptFile", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EncryptFile indicates an expected call of EncryptFile.
+func (mr *MockFileEncrypterMockRecorder) EncryptFile(src, dst interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{src, dst}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptFile", reflect.TypeOf((*MockFileEncrypter)(nil).EncryptFile), varargs...)
+}
+
+// EncryptFileName mocks base method.
+func (m *MockFileEncrypter) EncryptFileName(name string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncryptFileName", name)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncryptFileName indicates an expected call of EncryptFileName.
+func (mr *MockFileEncrypterMockRecorder) EncryptFileName(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptFileName", reflect.TypeOf((*MockFileEncrypter)(nil).EncryptFileName), name)
+}
+
+// NewDecryptFS mocks base method.
+func (m *MockFileEncrypter) NewDecryptFS(fsys fs.FS, encryptedNames bool) fs.FS {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewDecryptFS", fsys, encryptedNames)
+	ret0, _ := ret[0].(fs.FS)
+	return ret0
+}
+
+// NewDecryptFS indicates an expected call of NewDecryptFS.
+func (mr *MockFileEncrypterMockRecorder) NewDecryptFS(fsys, encryptedNames interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewDecryptFS", reflect.TypeOf((*MockFileEncrypter)(nil).NewDecryptFS), fsys, encryptedNames)
+}
+
+// MockEnvelopeService is a mock of EnvelopeService interface.
+type MockEnvelopeService struct {
+	ctrl     *gomock.Controller
+	recorder *MockEnvelopeServiceMockRecorder
+}
+
+// MockEnvelopeServiceMockRecorder is the mock recorder for MockEnvelopeService.
+type MockEnvelopeServiceMockRecorder struct {
+	mock *MockEnvelopeService
+}
+
+// NewMockEnvelopeService creates a new mock instance.
+func NewMockEnvelopeService(ctrl *gomock.Controller) *MockEnvelopeService {
+	mock := &MockEnvelopeService{ctrl: ctrl}
+	mock.recorder = &MockEnvelopeServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEnvelopeService) EXPECT() *MockEnvelopeServiceMockRecorder {
+	return m.recorder
+}
+
+// AddRecipient mocks base method.
+func (m *MockEnvelopeService) AddRecipient(ciphertext []byte, r encryption.Recipient) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRecipient", ciphertext, r)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddRecipient indicates an expected call of AddRecipient.
+func (mr *MockEnvelopeServiceMockRecorder) AddRecipient(ciphertext, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRecipient", reflect.TypeOf((*MockEnvelopeService)(nil).AddRecipient), ciphertext, r)
+}
+
+// DecryptEnvelope mocks base method.
+func (m *MockEnvelopeService) DecryptEnvelope(ciphertext []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptEnvelope", ciphertext)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptEnvelope indicates an expected call of DecryptEnvelope.
+func (mr *MockEnvelopeServiceMockRecorder) DecryptEnvelope(ciphertext interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptEnvelope", reflect.TypeOf((*MockEnvelopeService)(nil).DecryptEnvelope), ciphertext)
+}
+
+// MockSigner is a mock of Signer interface.
+type MockSigner struct {
+	ctrl     *gomock.Controller
+	recorder *MockSignerMockRecorder
+}
+
+// MockSignerMockRecorder is the mock recorder for MockSigner.
+type MockSignerMockRecorder struct {
+	mock *MockSigner
+}
+
+// NewMockSigner creates a new mock instance.
+func NewMockSigner(ctrl *gomock.Controller) *MockSigner {
+	mock := &MockSigner{ctrl: ctrl}
+	mock.recorder = &MockSignerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSigner) EXPECT() *MockSignerMockRecorder {
+	return m.recorder
+}
+
+// DecryptAndVerify mocks base method.
+func (m *MockSigner) DecryptAndVerify(ciphertext []byte, trusted ...ed25519.PublicKey) ([]byte, ed25519.PublicKey, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ciphertext}
+	for _, a := range trusted {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DecryptAndVerify", varargs...)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(ed25519.PublicKey)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// DecryptAndVerify indicates an expected call of DecryptAndVerify.
+func (mr *MockSignerMockRecorder) DecryptAndVerify(ciphertext interface{}, trusted ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ciphertext}, trusted...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptAndVerify", reflect.TypeOf((*MockSigner)(nil).DecryptAndVerify), varargs...)
+}
+
+// Sign mocks base method.
+func (m *MockSigner) Sign(document []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sign", document)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sign indicates an expected call of Sign.
+func (mr *MockSignerMockRecorder) Sign(document interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sign", reflect.TypeOf((*MockSigner)(nil).Sign), document)
+}
+
+// SignAndEncrypt mocks base method.
+func (m *MockSigner) SignAndEncrypt(plaintext []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignAndEncrypt", plaintext)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignAndEncrypt indicates an expected call of SignAndEncrypt.
+func (mr *MockSignerMockRecorder) SignAndEncrypt(plaintext interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignAndEncrypt", reflect.TypeOf((*MockSigner)(nil).SignAndEncrypt), plaintext)
+}
+
+// SignPlainFields mocks base method.
+func (m *MockSigner) SignPlainFields(eData interface{}) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignPlainFields", eData)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignPlainFields indicates an expected call of SignPlainFields.
+func (mr *MockSignerMockRecorder) SignPlainFields(eData interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignPlainFields", reflect.TypeOf((*MockSigner)(nil).SignPlainFields), eData)
+}
+
+// Verify mocks base method.
+func (m *MockSigner) Verify(document, signature []byte, trusted ...ed25519.PublicKey) (ed25519.PublicKey, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{document, signature}
+	for _, a := range trusted {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Verify", varargs...)
+	ret0, _ := ret[0].(ed25519.PublicKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockSignerMockRecorder) Verify(document, signature interface{}, trusted ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{document, signature}, trusted...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockSigner)(nil).Verify), varargs...)
+}
+
+// VerifyPlainFields mocks base method.
+func (m *MockSigner) VerifyPlainFields(eData interface{}, signature []byte, trusted ...ed25519.PublicKey) (ed25519.PublicKey, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{eData, signature}
+	for _, a := range trusted {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "VerifyPlainFields", varargs...)
+	ret0, _ := ret[0].(ed25519.PublicKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyPlainFields indicates an expected call of VerifyPlainFields.
+func (mr *MockSignerMockRecorder) VerifyPlainFields(eData, signature interface{}, trusted ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{eData, signature}, trusted...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyPlainFields", reflect.TypeOf((*MockSigner)(nil).VerifyPlainFields), varargs...)
+}
+
+// WithSigningKey mocks base method.
+func (m *MockSigner) WithSigningKey(signingKey ed25519.PrivateKey) (encryption.Signer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithSigningKey", signingKey)
+	ret0, _ := ret[0].(encryption.Signer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WithSigningKey indicates an expected call of WithSigningKey.
+func (mr *MockSignerMockRecorder) WithSigningKey(signingKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithSigningKey", reflect.TypeOf((*MockSigner)(nil).WithSigningKey), signingKey)
+}
+
+// MockDocumentAuthenticator is a mock of DocumentAuthenticator interface.
+type MockDocumentAuthenticator struct {
+	ctrl     *gomock.Controller
+	recorder *MockDocumentAuthenticatorMockRecorder
+}
+
+// MockDocumentAuthenticatorMockRecorder is the mock recorder for MockDocumentAuthenticator.
+type MockDocumentAuthenticatorMockRecorder struct {
+	mock *MockDocumentAuthenticator
+}
+
+// NewMockDocumentAuthenticator creates a new mock instance.
+func NewMockDocumentAuthenticator(ctrl *gomock.Controller) *MockDocumentAuthenticator {
+	mock := &MockDocumentAuthenticator{ctrl: ctrl}
+	mock.recorder = &MockDocumentAuthenticatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDocumentAuthenticator) EXPECT() *MockDocumentAuthenticatorMockRecorder {
+	return m.recorder
+}
+
+// WithDocumentMAC mocks base method.
+func (m *MockDocumentAuthenticator) WithDocumentMAC() (encryption.EncryptionService, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithDocumentMAC")
+	ret0, _ := ret[0].(encryption.EncryptionService)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WithDocumentMAC indicates an expected call of WithDocumentMAC.
+func (mr *MockDocumentAuthenticatorMockRecorder) WithDocumentMAC() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithDocumentMAC", reflect.TypeOf((*MockDocumentAuthenticator)(nil).WithDocumentMAC))
+}
+
+// MockDocumentEncrypter is a mock of DocumentEncrypter interface.
+type MockDocumentEncrypter struct {
+	ctrl     *gomock.Controller
+	recorder *MockDocumentEncrypterMockRecorder
+}
+
+// MockDocumentEncrypterMockRecorder is the mock recorder for MockDocumentEncrypter.
+type MockDocumentEncrypterMockRecorder struct {
+	mock *MockDocumentEncrypter
+}
+
+// NewMockDocumentEncrypter creates a new mock instance.
+func NewMockDocumentEncrypter(ctrl *gomock.Controller) *MockDocumentEncrypter {
+	mock := &MockDocumentEncrypter{ctrl: ctrl}
+	mock.recorder = &MockDocumentEncrypterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDocumentEncrypter) EXPECT() *MockDocumentEncrypterMockRecorder {
+	return m.recorder
+}
+
+// DecryptJSONDocument mocks base method.
+func (m *MockDocumentEncrypter) DecryptJSONDocument(doc []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptJSONDocument", doc)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptJSONDocument indicates an expected call of DecryptJSONDocument.
+func (mr *MockDocumentEncrypterMockRecorder) DecryptJSONDocument(doc interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptJSONDocument", reflect.TypeOf((*MockDocumentEncrypter)(nil).DecryptJSONDocument), doc)
+}
+
+// DecryptMap mocks base method.
+func (m *MockDocumentEncrypter) DecryptMap(doc map[string]interface{}, policy encryption.FieldPolicy) (map[string]interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptMap", doc, policy)
+	ret0, _ := ret[0].(map[string]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptMap indicates an expected call of DecryptMap.
+func (mr *MockDocumentEncrypterMockRecorder) DecryptMap(doc, policy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptMap", reflect.TypeOf((*MockDocumentEncrypter)(nil).DecryptMap), doc, policy)
+}
+
+// EncryptJSONDocument mocks base method.
+func (m *MockDocumentEncrypter) EncryptJSONDocument(doc []byte, selectors []string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncryptJSONDocument", doc, selectors)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncryptJSONDocument indicates an expected call of EncryptJSONDocument.
+func (mr *MockDocumentEncrypterMockRecorder) EncryptJSONDocument(doc, selectors interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptJSONDocument", reflect.TypeOf((*MockDocumentEncrypter)(nil).EncryptJSONDocument), doc, selectors)
+}
+
+// EncryptMap mocks base method.
+func (m *MockDocumentEncrypter) EncryptMap(doc map[string]interface{}, policy encryption.FieldPolicy) (map[string]interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncryptMap", doc, policy)
+	ret0, _ := ret[0].(map[string]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncryptMap indicates an expected call of EncryptMap.
+func (mr *MockDocumentEncrypterMockRecorder) EncryptMap(doc, policy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptMap", reflect.TypeOf((*MockDocumentEncrypter)(nil).EncryptMap), doc, policy)
+}
+
+// MockExpiringEncrypter is a mock of ExpiringEncrypter interface.
+type MockExpiringEncrypter struct {
+	ctrl     *gomock.Controller
+	recorder *MockExpiringEncrypterMockRecorder
+}
+
+// MockExpiringEncrypterMockRecorder is the mock recorder for MockExpiringEncrypter.
+type MockExpiringEncrypterMockRecorder struct {
+	mock *MockExpiringEncrypter
+}
+
+// NewMockExpiringEncrypter creates a new mock instance.
+func NewMockExpiringEncrypter(ctrl *gomock.Controller) *MockExpiringEncrypter {
+	mock := &MockExpiringEncrypter{ctrl: ctrl}
+	mock.recorder = &MockExpiringEncrypterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExpiringEncrypter) EXPECT() *MockExpiringEncrypterMockRecorder {
+	return m.recorder
+}
+
+// DecryptBytWithTTL mocks base method.
+func (m *MockExpiringEncrypter) DecryptBytWithTTL(token string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptBytWithTTL", token)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptBytWithTTL indicates an expected call of DecryptBytWithTTL.
+func (mr *MockExpiringEncrypterMockRecorder) DecryptBytWithTTL(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptBytWithTTL", reflect.TypeOf((*MockExpiringEncrypter)(nil).DecryptBytWithTTL), token)
+}
+
+// DecryptStrWithTTL mocks base method.
+func (m *MockExpiringEncrypter) DecryptStrWithTTL(token string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptStrWithTTL", token)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptStrWithTTL indicates an expected call of DecryptStrWithTTL.
+func (mr *MockExpiringEncrypterMockRecorder) DecryptStrWithTTL(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptStrWithTTL", reflect.TypeOf((*MockExpiringEncrypter)(nil).DecryptStrWithTTL), token)
+}
+
+// EncryptBytWithTTL mocks base method.
+func (m *MockExpiringEncrypter) EncryptBytWithTTL(b []byte, ttl time.Duration) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncryptBytWithTTL", b, ttl)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncryptBytWithTTL indicates an expected call of EncryptBytWithTTL.
+func (mr *MockExpiringEncrypterMockRecorder) EncryptBytWithTTL(b, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptBytWithTTL", reflect.TypeOf((*MockExpiringEncrypter)(nil).EncryptBytWithTTL), b, ttl)
+}
+
+// EncryptStrWithTTL mocks base method.
+func (m *MockExpiringEncrypter) EncryptStrWithTTL(str string, ttl time.Duration) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncryptStrWithTTL", str, ttl)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncryptStrWithTTL indicates an expected call of EncryptStrWithTTL.
+func (mr *MockExpiringEncrypterMockRecorder) EncryptStrWithTTL(str, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptStrWithTTL", reflect.TypeOf((*MockExpiringEncrypter)(nil).EncryptStrWithTTL), str, ttl)
+}
+
+// WithClock mocks base method.
+func (m *MockExpiringEncrypter) WithClock(clock func() time.Time) encryption.ExpiringEncrypter {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithClock", clock)
+	ret0, _ := ret[0].(encryption.ExpiringEncrypter)
+	return ret0
+}
+
+// WithClock indicates an expected call of WithClock.
+func (mr *MockExpiringEncrypterMockRecorder) WithClock(clock interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithClock", reflect.TypeOf((*MockExpiringEncrypter)(nil).WithClock), clock)
+}
+
+// MockJWEEncrypter is a mock of JWEEncrypter interface.
+type MockJWEEncrypter struct {
+	ctrl     *gomock.Controller
+	recorder *MockJWEEncrypterMockRecorder
+}
+
+// MockJWEEncrypterMockRecorder is the mock recorder for MockJWEEncrypter.
+type MockJWEEncrypterMockRecorder struct {
+	mock *MockJWEEncrypter
+}
+
+// NewMockJWEEncrypter creates a new mock instance.
+func NewMockJWEEncrypter(ctrl *gomock.Controller) *MockJWEEncrypter {
+	mock := &MockJWEEncrypter{ctrl: ctrl}
+	mock.recorder = &MockJWEEncrypterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJWEEncrypter) EXPECT() *MockJWEEncrypterMockRecorder {
+	return m.recorder
+}
+
+// DecryptJWE mocks base method.
+func (m *MockJWEEncrypter) DecryptJWE(token string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptJWE", token)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptJWE indicates an expected call of DecryptJWE.
+func (mr *MockJWEEncrypterMockRecorder) DecryptJWE(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptJWE", reflect.TypeOf((*MockJWEEncrypter)(nil).DecryptJWE), token)
+}
+
+// EncryptJWE mocks base method.
+func (m *MockJWEEncrypter) EncryptJWE(b []byte, alg string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncryptJWE", b, alg)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncryptJWE indicates an expected call of EncryptJWE.
+func (mr *MockJWEEncrypterMockRecorder) EncryptJWE(b, alg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptJWE", reflect.TypeOf((*MockJWEEncrypter)(nil).EncryptJWE), b, alg)
+}
+
+// ExportJWK mocks base method.
+func (m *MockJWEEncrypter) ExportJWK() ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportJWK")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportJWK indicates an expected call of ExportJWK.
+func (mr *MockJWEEncrypterMockRecorder) ExportJWK() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportJWK", reflect.TypeOf((*MockJWEEncrypter)(nil).ExportJWK))
+}
+
+// MockBlindIndexer is a mock of BlindIndexer interface.
+type MockBlindIndexer struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlindIndexerMockRecorder
+}
+
+// MockBlindIndexerMockRecorder is the mock recorder for MockBlindIndexer.
+type MockBlindIndexerMockRecorder struct {
+	mock *MockBlindIndexer
+}
+
+// NewMockBlindIndexer creates a new mock instance.
+func NewMockBlindIndexer(ctrl *gomock.Controller) *MockBlindIndexer {
+	mock := &MockBlindIndexer{ctrl: ctrl}
+	mock.recorder = &MockBlindIndexerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlindIndexer) EXPECT() *MockBlindIndexerMockRecorder {
+	return m.recorder
+}
+
+// RangeTokens mocks base method.
+func (m *MockBlindIndexer) RangeTokens(model interface{}, fieldName string, from, to interface{}) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RangeTokens", model, fieldName, from, to)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RangeTokens indicates an expected call of RangeTokens.
+func (mr *MockBlindIndexerMockRecorder) RangeTokens(model, fieldName, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RangeTokens", reflect.TypeOf((*MockBlindIndexer)(nil).RangeTokens), model, fieldName, from, to)
+}
+
+// SearchTokens mocks base method.
+func (m *MockBlindIndexer) SearchTokens(model interface{}, fieldName, term string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchTokens", model, fieldName, term)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchTokens indicates an expected call of SearchTokens.
+func (mr *MockBlindIndexerMockRecorder) SearchTokens(model, fieldName, term interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchTokens", reflect.TypeOf((*MockBlindIndexer)(nil).SearchTokens), model, fieldName, term)
+}
@@ -0,0 +1,279 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+//Define OpenSSL enc format constants
+const (
+	OpenSSLMagic             = "Salted__"
+	OpenSSLDefaultIterations = 10000
+	openSSLSaltSize          = 8
+	openSSLHeaderSize        = len(OpenSSLMagic) + openSSLSaltSize
+	openSSLKeySize           = 32
+	openSSLReadSize          = 32 * 1024
+)
+
+//Option for the OpenSSL readers and writers
+type OpenSSLOption func(*openSSLOptions)
+
+type openSSLOptions struct {
+	pbkdf2     bool
+	iterations int
+	digest     func() hash.Hash
+}
+
+//Derive the key with PBKDF2 like openssl enc -pbkdf2, iterations of 0 uses the OpenSSL default of 10000
+func WithPBKDF2(iterations int) OpenSSLOption {
+	return func(o *openSSLOptions) {
+		o.pbkdf2 = true
+		o.iterations = iterations
+	}
+}
+
+//Use another message digest for key derivation like openssl enc -md, the default is SHA-256
+func WithMessageDigest(digest func() hash.Hash) OpenSSLOption {
+	return func(o *openSSLOptions) {
+		o.digest = digest
+	}
+}
+
+//Derive AES-256 key and CBC IV from password and salt
+func (o openSSLOptions) deriveKeyIV(password, salt []byte) ([]byte, []byte) {
+	var keyIV []byte
+	if o.pbkdf2 {
+		keyIV = pbkdf2Key(o.digest, password, salt, o.iterations, openSSLKeySize+aes.BlockSize)
+	} else {
+		keyIV = evpBytesToKey(o.digest, password, salt, openSSLKeySize+aes.BlockSize)
+	}
+
+	return keyIV[:openSSLKeySize], keyIV[openSSLKeySize:]
+}
+
+func newOpenSSLOptions(opts []OpenSSLOption) openSSLOptions {
+	options := openSSLOptions{digest: sha256.New}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.iterations <= 0 {
+		options.iterations = OpenSSLDefaultIterations
+	}
+
+	return options
+}
+
+//Legacy key derivation of OpenSSL with a single iteration
+func evpBytesToKey(digest func() hash.Hash, password, salt []byte, length int) []byte {
+	var out, prev []byte
+	for len(out) < length {
+		h := digest()
+		h.Write(prev)
+		h.Write(password)
+		h.Write(salt)
+		prev = h.Sum(nil)
+		out = append(out, prev...)
+	}
+
+	return out[:length]
+}
+
+//Derive key as described in RFC 8018
+func pbkdf2Key(digest func() hash.Hash, password, salt []byte, iterations, length int) []byte {
+	prf := hmac.New(digest, password)
+
+	var out []byte
+	for block := uint32(1); len(out) < length; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, block))
+		u := prf.Sum(nil)
+
+		t := append([]byte{}, u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+
+		out = append(out, t...)
+	}
+
+	return out[:length]
+}
+
+type openSSLWriter struct {
+	w       io.Writer
+	cbc     cipher.BlockMode
+	pending []byte
+	closed  bool
+}
+
+//Create writer that encrypts to the format of openssl enc -aes-256-cbc -salt, Close must be called to write the padding
+func NewOpenSSLWriter(w io.Writer, password []byte, opts ...OpenSSLOption) (io.WriteCloser, error) {
+	options := newOpenSSLOptions(opts)
+
+	salt := make([]byte, openSSLSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	key, iv := options.deriveKeyIV(password, salt)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher, external aes package returned the following error: %s", err)
+	}
+
+	if _, err := w.Write(append([]byte(OpenSSLMagic), salt...)); err != nil {
+		return nil, err
+	}
+
+	return &openSSLWriter{w: w, cbc: cipher.NewCBCEncrypter(block, iv)}, nil
+}
+
+func (o *openSSLWriter) Write(p []byte) (int, error) {
+	if o.closed {
+		return 0, errors.New("write to closed OpenSSL writer")
+	}
+
+	o.pending = append(o.pending, p...)
+
+	//Encrypt all complete blocks, the rest is kept until more data or Close
+	n := len(o.pending) / aes.BlockSize * aes.BlockSize
+	if n == 0 {
+		return len(p), nil
+	}
+
+	out := make([]byte, n)
+	o.cbc.CryptBlocks(out, o.pending[:n])
+	o.pending = append(o.pending[:0], o.pending[n:]...)
+
+	if _, err := o.w.Write(out); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (o *openSSLWriter) Close() error {
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+
+	//PKCS7 padding, a full block is added when the input is already aligned
+	padding := aes.BlockSize - len(o.pending)
+	last := append(o.pending, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	o.cbc.CryptBlocks(last, last)
+
+	_, err := o.w.Write(last)
+
+	return err
+}
+
+type openSSLReader struct {
+	r   io.Reader
+	cbc cipher.BlockMode
+	in  []byte
+	out []byte
+	eof bool
+	err error
+}
+
+//Create reader that decrypts data created by openssl enc -aes-256-cbc -salt, the data is not authenticated
+func NewOpenSSLReader(r io.Reader, password []byte, opts ...OpenSSLOption) (io.Reader, error) {
+	options := newOpenSSLOptions(opts)
+
+	header := make([]byte, openSSLHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read OpenSSL header, the following error occured: %s", err)
+	}
+	if !bytes.Equal(header[:len(OpenSSLMagic)], []byte(OpenSSLMagic)) {
+		return nil, errors.New("input is not in the OpenSSL salted format")
+	}
+
+	key, iv := options.deriveKeyIV(password, header[len(OpenSSLMagic):])
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher, external aes package returned the following error: %s", err)
+	}
+
+	return &openSSLReader{r: r, cbc: cipher.NewCBCDecrypter(block, iv)}, nil
+}
+
+func (o *openSSLReader) Read(p []byte) (int, error) {
+	for len(o.out) == 0 {
+		if o.err != nil {
+			return 0, o.err
+		}
+		if o.eof {
+			return 0, io.EOF
+		}
+
+		o.fill()
+	}
+
+	n := copy(p, o.out)
+	o.out = o.out[n:]
+
+	return n, nil
+}
+
+//Read more ciphertext and decrypt all blocks except the last one, which holds the padding
+func (o *openSSLReader) fill() {
+	buf := make([]byte, openSSLReadSize)
+	n, err := o.r.Read(buf)
+	o.in = append(o.in, buf[:n]...)
+
+	if err != nil && err != io.EOF {
+		o.err = err
+		return
+	}
+
+	if err == io.EOF {
+		o.eof = true
+		if len(o.in) == 0 || len(o.in)%aes.BlockSize != 0 {
+			o.err = errors.New("OpenSSL ciphertext is not a multiple of the block size")
+			return
+		}
+
+		plain := make([]byte, len(o.in))
+		o.cbc.CryptBlocks(plain, o.in)
+		o.in = nil
+
+		padding := int(plain[len(plain)-1])
+		if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+			o.err = errors.New("OpenSSL ciphertext has invalid padding, the password or options may be wrong")
+			return
+		}
+
+		o.out = plain[:len(plain)-padding]
+		return
+	}
+
+	keep := len(o.in) - (len(o.in)-1)/aes.BlockSize*aes.BlockSize
+	if len(o.in) == 0 {
+		keep = 0
+	}
+
+	n = len(o.in) - keep
+	if n > 0 {
+		plain := make([]byte, n)
+		o.cbc.CryptBlocks(plain, o.in[:n])
+		o.in = append(o.in[:0], o.in[n:]...)
+		o.out = plain
+	}
+}
@@ -0,0 +1,159 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_pbkdf2Key(t *testing.T) {
+	//Vectors for PBKDF2-HMAC-SHA256 with password "password" and salt "salt"
+	tests := []struct {
+		iterations int
+		want       string
+	}{
+		{iterations: 1, want: "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{iterations: 2, want: "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{iterations: 4096, want: "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2Key(sha256.New, []byte("password"), []byte("salt"), tt.iterations, 32))
+		if got != tt.want {
+			t.Errorf("pbkdf2Key() with %d iterations = %v, want %v", tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func Test_NewOpenSSLReader(t *testing.T) {
+	//Created with: printf 'hello from openssl\n' | openssl enc -aes-256-cbc <options> -pass pass:secret | base64
+	tests := []struct {
+		name       string
+		ciphertext string
+		password   string
+		opts       []OpenSSLOption
+		wantErr    bool
+	}{
+		{
+			name:       "legacy md5",
+			ciphertext: "U2FsdGVkX1+uuxLVC3B4cekadkboRUaJ7DJs+yj5FEGq+1A/cWRBUvL0UMSuVUVq",
+			password:   "secret",
+			opts:       []OpenSSLOption{WithMessageDigest(md5.New)},
+		},
+		{
+			name:       "legacy sha256",
+			ciphertext: "U2FsdGVkX18COuKME6jA4sPUVCWGac3+e5+yyle+KrFs0PpvFaiDtFeWY44yB2ah",
+			password:   "secret",
+		},
+		{
+			name:       "pbkdf2",
+			ciphertext: "U2FsdGVkX18aF8vvpmu/qYTUSmkyXoVvaXWMT6S/ukH9N4VPMvCdTFDgkzQzz+jz",
+			password:   "secret",
+			opts:       []OpenSSLOption{WithPBKDF2(0)},
+		},
+		{
+			name:       "pbkdf2 sha512 with 1000 iterations",
+			ciphertext: "U2FsdGVkX18z+NkgChUzGUEZUDwG4PYLZn+sK6Jz5IzPPdKcy7VfDY/la7G8xdJP",
+			password:   "secret",
+			opts:       []OpenSSLOption{WithPBKDF2(1000), WithMessageDigest(sha512.New)},
+		},
+		{
+			name:       "wrong password",
+			ciphertext: "U2FsdGVkX18aF8vvpmu/qYTUSmkyXoVvaXWMT6S/ukH9N4VPMvCdTFDgkzQzz+jz",
+			password:   "wrong",
+			opts:       []OpenSSLOption{WithPBKDF2(0)},
+			wantErr:    true,
+		},
+		{
+			name:       "truncated",
+			ciphertext: "U2FsdGVkX18aF8vvpmu/qYTUSmkyXoVvaXWMT6S/ukH9N4VPMvCdTFDg",
+			password:   "secret",
+			opts:       []OpenSSLOption{WithPBKDF2(0)},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, err := base64.StdEncoding.DecodeString(tt.ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := NewOpenSSLReader(bytes.NewReader(ciphertext), []byte(tt.password), tt.opts...)
+			if err != nil {
+				t.Fatalf("NewOpenSSLReader() error = %v", err)
+			}
+
+			got, err := io.ReadAll(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("openSSLReader.Read() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && string(got) != "hello from openssl\n" {
+				t.Errorf("openSSLReader.Read() = %q, want %q", got, "hello from openssl\n")
+			}
+		})
+	}
+}
+
+func Test_NewOpenSSLWriter(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		opts []OpenSSLOption
+	}{
+		{name: "empty", size: 0},
+		{name: "single block", size: 16},
+		{name: "partial block", size: 21, opts: []OpenSSLOption{WithPBKDF2(0)}},
+		{name: "multiple reads", size: 3*openSSLReadSize + 5, opts: []OpenSSLOption{WithPBKDF2(100)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := bytes.Repeat([]byte{'a'}, tt.size)
+
+			var buf bytes.Buffer
+			w, err := NewOpenSSLWriter(&buf, []byte("secret"), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			//Write in uneven pieces to cover blocks spanning multiple writes
+			for rest := plain; len(rest) > 0; {
+				n := 7
+				if n > len(rest) {
+					n = len(rest)
+				}
+				if _, err := w.Write(rest[:n]); err != nil {
+					t.Fatal(err)
+				}
+				rest = rest[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.HasPrefix(buf.Bytes(), []byte(OpenSSLMagic)) || (buf.Len()-openSSLHeaderSize)%16 != 0 {
+				t.Fatalf("unexpected OpenSSL output of %d bytes", buf.Len())
+			}
+
+			r, err := NewOpenSSLReader(iotest.OneByteReader(&buf), []byte("secret"), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("openSSLReader.Read() error = %v", err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("openSSLReader.Read() returned %d bytes, want %d", len(got), len(plain))
+			}
+		})
+	}
+}
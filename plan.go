@@ -0,0 +1,172 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+//Tags holding the field name, in the order in which they are tried by the struct functions
+var (
+	interfaceTagNames = []string{"bson", "ename"}
+	jsonTagNames      = []string{"bson", "json"}
+	decryptTagNames   = []string{"bson", "ename", "json"}
+)
+
+//Compiled plans of struct types, the tags of a type are parsed once and reused by every call
+var structPlans sync.Map
+
+//Everything the struct functions need to know about a struct type
+type structPlan struct {
+	fields []fieldPlan
+	//Invalid encrypted tags of all fields, the type cannot be used by the struct functions at all
+	err error
+	//Names of the <field>_search and <field>_range fields generated for the fields with an index, mapped to the index of the field
+	indexNames map[string]int
+}
+
+//Field of a struct type with its parsed tags
+type fieldPlan struct {
+	index int
+	field reflect.StructField
+	//Values of the bson, ename and json tags
+	names map[string]string
+	//Whether the field is encrypted, every field is unless it is tagged encrypted:"false"
+	encrypted bool
+	options   fieldOptions
+	//Error of the encrypted tag, including an aad option naming a field that cannot be used
+	optionsErr error
+	//Type name used by Encode and Decode to pick the conversion
+	typeName string
+
+	search    *searchIndex
+	searchErr error
+	rng       *rangeIndex
+	rangeErr  error
+}
+
+//Get the plan of a struct type, it is compiled on first use
+func planFor(t reflect.Type) *structPlan {
+	if plan, ok := structPlans.Load(t); ok {
+		return plan.(*structPlan)
+	}
+
+	plan := &structPlan{fields: make([]fieldPlan, t.NumField()), indexNames: map[string]int{}}
+	for i := range plan.fields {
+		field := t.Field(i)
+		f := &plan.fields[i]
+
+		f.index, f.field = i, field
+		f.encrypted, f.options, f.optionsErr = parseEncryptedTag(field.Tag.Get("encrypted"))
+		f.typeName = field.Type.String()
+
+		f.names = map[string]string{}
+		for _, tag := range decryptTagNames {
+			if name := field.Tag.Get(tag); name != "" {
+				f.names[tag] = name
+			}
+		}
+
+		//Invalid index tags only fail the calls that use them, like before plans were cached
+		f.search, f.searchErr = parseSearchTag(field.Tag.Get("search"))
+		f.rng, f.rangeErr = parseRangeTag(field.Tag.Get("range"), field.Type)
+
+		for _, name := range f.names {
+			if f.search != nil {
+				plan.indexNames[name+SearchFieldSuffix] = i
+			}
+			if f.rng != nil {
+				plan.indexNames[name+RangeFieldSuffix] = i
+			}
+		}
+	}
+
+	//Associated data can only refer to the other fields once all of them are known
+	var errs []error
+	for i := range plan.fields {
+		f := &plan.fields[i]
+		if f.optionsErr == nil && f.options.aad != "" {
+			f.options.aadIndex, f.optionsErr = plan.aadField(f)
+		}
+		if f.optionsErr != nil {
+			errs = append(errs, fmt.Errorf("invalid encrypted tag on field %s: %s", f.field.Name, f.optionsErr))
+		}
+	}
+	plan.err = errors.Join(errs...)
+
+	//Another goroutine may have compiled the same type in the meantime, both plans are equal
+	actual, _ := structPlans.LoadOrStore(t, plan)
+
+	return actual.(*structPlan)
+}
+
+//Find a field by its Go name or its bson, ename or json name
+func (p *structPlan) lookup(name string) (int, bool) {
+	for i := range p.fields {
+		if p.fields[i].field.Name == name {
+			return i, true
+		}
+		for _, tagName := range p.fields[i].names {
+			if tagName == name {
+				return i, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+//Get the index of the field named by the aad option, it has to be stored in plain text so that Decrypt can read it
+func (p *structPlan) aadField(f *fieldPlan) (int, error) {
+	i, ok := p.lookup(f.options.aad)
+	switch {
+	case !ok:
+		return 0, fmt.Errorf("aad field %s does not exist", f.options.aad)
+	case i == f.index:
+		return 0, errors.New("aad field cannot be the field itself")
+	case p.fields[i].encrypted:
+		return 0, fmt.Errorf("aad field %s has to be tagged encrypted:\"false\"", f.options.aad)
+	}
+
+	return i, nil
+}
+
+//Get the field name from the first of the given tags that is set
+func (f *fieldPlan) name(fieldTagNames []string) (string, error) {
+	for _, tag := range fieldTagNames {
+		if name, ok := f.names[tag]; ok {
+			return name, nil
+		}
+	}
+
+	return "", errors.New("none of the necessary field tags were found in the structure could not map to interface")
+}
+
+//Encode value of the field like Encode without looking up the type name again
+func (f *fieldPlan) encode(value reflect.Value) string {
+	return encodeType(f.typeName, value)
+}
+
+//Get the associated data of the field from the plain field named by the aad option
+func (f *fieldPlan) aad(object reflect.Value) []byte {
+	if f.options.aad == "" {
+		return nil
+	}
+
+	return []byte(fmt.Sprint(object.Field(f.options.aadIndex)))
+}
+
+//Get the associated data of the field from the matching plain field of an encrypted struct
+func (f *fieldPlan) encryptedAAD(plan *structPlan, object reflect.Value) ([]byte, error) {
+	if f.options.aad == "" {
+		return nil, nil
+	}
+
+	i, ok := plan.lookup(f.options.aad)
+	if !ok {
+		return nil, fmt.Errorf("aad field %s of field %s could not be found in the encrypted structure", f.options.aad, f.field.Name)
+	}
+
+	return []byte(fmt.Sprint(object.Field(i))), nil
+}
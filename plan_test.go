@@ -0,0 +1,142 @@
+package encryption
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type planModel struct {
+	Id     string `bson:"_id" encrypted:"false"`
+	Name   string `ename:"name" json:"full_name" search:"prefix"`
+	Age    int    `json:"age" range:"width=10"`
+	NoName string
+	Broken string `bson:"broken" search:"unknown"`
+}
+
+func Test_planFor(t *testing.T) {
+	plan := planFor(reflect.TypeOf(planModel{}))
+
+	if again := planFor(reflect.TypeOf(planModel{})); again != plan {
+		t.Errorf("planFor() compiled the same type twice")
+	}
+
+	tests := []struct {
+		field         string
+		tagNames      []string
+		wantName      string
+		wantErr       bool
+		wantEncrypted bool
+		wantSearch    bool
+		wantRange     bool
+		wantIndexErr  bool
+	}{
+		{field: "Id", tagNames: interfaceTagNames, wantName: "_id"},
+		{field: "Name", tagNames: interfaceTagNames, wantName: "name", wantEncrypted: true, wantSearch: true},
+		{field: "Name", tagNames: jsonTagNames, wantName: "full_name", wantEncrypted: true, wantSearch: true},
+		{field: "Age", tagNames: interfaceTagNames, wantErr: true, wantEncrypted: true, wantRange: true},
+		{field: "Age", tagNames: decryptTagNames, wantName: "age", wantEncrypted: true, wantRange: true},
+		{field: "NoName", tagNames: decryptTagNames, wantErr: true, wantEncrypted: true},
+		{field: "Broken", tagNames: decryptTagNames, wantName: "broken", wantEncrypted: true, wantIndexErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			var field *fieldPlan
+			for i := range plan.fields {
+				if plan.fields[i].field.Name == tt.field {
+					field = &plan.fields[i]
+				}
+			}
+
+			name, err := field.name(tt.tagNames)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fieldPlan.name() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if name != tt.wantName {
+				t.Errorf("fieldPlan.name() = %v, want %v", name, tt.wantName)
+			}
+			if field.encrypted != tt.wantEncrypted {
+				t.Errorf("fieldPlan.encrypted = %v, want %v", field.encrypted, tt.wantEncrypted)
+			}
+			if (field.search != nil) != tt.wantSearch || (field.rng != nil) != tt.wantRange || (field.searchErr != nil) != tt.wantIndexErr {
+				t.Errorf("fieldPlan indexes = %v %v %v, want %v %v %v", field.search, field.rng, field.searchErr, tt.wantSearch, tt.wantRange, tt.wantIndexErr)
+			}
+		})
+	}
+}
+
+//Plans are shared between goroutines, run with -race
+func Test_planFor_Concurrent(t *testing.T) {
+	e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+
+	type concurrentModel struct {
+		Name string `bson:"name"`
+		Age  int    `bson:"age"`
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			encrypted, err := e.EncryptToInterface(concurrentModel{Name: "alice", Age: i})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			name, err := e.DecryptStr(encrypted["name"].([]byte))
+			if err != nil || name != "alice" {
+				t.Errorf("DecryptStr() = %v, %v, want alice", name, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func benchmarkModel() stringfloatbool {
+	return stringfloatbool{String: "123Test", Float64: 64.64, Bool: true, StringArr: []string{"a", "b"}, EmptyVal: "value"}
+}
+
+func Benchmark_EncryptToInterface(b *testing.B) {
+	e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+	model := benchmarkModel()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := e.EncryptToInterface(model); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_EncryptToJSON(b *testing.B) {
+	e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+	model := benchmarkModel()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := e.EncryptToJSON(model); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Decrypt(b *testing.B) {
+	e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+	m := benchmarkModel()
+
+	var encrypted stringfloatboolEnc
+	encrypted.String = m.String
+	encrypted.Float64, _ = e.EncryptStr("64.64")
+	encrypted.Bool, _ = e.EncryptStr("true")
+	encrypted.StringArr, _ = e.EncryptStr("a°b")
+	encrypted.EmptyVal, _ = e.EncryptStr("value")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := e.Decrypt(encrypted, stringfloatbool{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Define range index defaults
+const (
+	RangeFieldSuffix = "_range"
+	RangeMaxTokens   = 1024
+	rangeIndexLabel  = "globe-protocol/encryption range index"
+)
+
+//Parsed range tag of a single struct field
+type rangeIndex struct {
+	width float64
+	//Width of integer fields, integers are bucketed without converting them to float64
+	intWidth uint64
+	duration time.Duration
+}
+
+//Parse range tag, e.g. `range:"width=1000"` for numbers or `range:"width=720h"` for time.Time
+func parseRangeTag(tag string, t reflect.Type) (*rangeIndex, error) {
+	if tag == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(tag, "width=") || strings.Contains(tag, ",") {
+		return nil, fmt.Errorf("range tag %q should be of the form width=<bucket width>", tag)
+	}
+	value := strings.TrimPrefix(tag, "width=")
+
+	idx := &rangeIndex{}
+	switch {
+	case t.String() == Time:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("range width of time field should be a duration, got %q", value)
+		}
+		if d < time.Second {
+			return nil, errors.New("range width of time field should be at least one second")
+		}
+		idx.duration = d
+
+	case isIntegerKind(t.Kind()):
+		w, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || w == 0 {
+			return nil, fmt.Errorf("range width of integer field should be a positive whole number, got %q", value)
+		}
+		idx.intWidth = w
+
+	case isNumericKind(t.Kind()):
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil || w <= 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+			return nil, fmt.Errorf("range width of numeric field should be a positive number, got %q", value)
+		}
+		idx.width = w
+
+	default:
+		return nil, fmt.Errorf("%s is not a supported type for a range index", t.String())
+	}
+
+	return idx, nil
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	return isNumericKind(k) && k != reflect.Float32 && k != reflect.Float64
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+//Get the number of the bucket the value falls in
+func (r *rangeIndex) bucket(value reflect.Value) (int64, error) {
+	if r.duration != 0 {
+		t, ok := value.Interface().(time.Time)
+		if !ok {
+			return 0, fmt.Errorf("expected time.Time value for range index, got %s", value.Type().String())
+		}
+
+		seconds := int64(r.duration / time.Second)
+		unix := t.Unix()
+		//Round down for dates before 1970 as well
+		if unix < 0 && unix%seconds != 0 {
+			return unix/seconds - 1, nil
+		}
+
+		return unix / seconds, nil
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v := value.Int()
+		//Every value falls in bucket 0 or -1 when the width does not fit in an int64
+		if r.intWidth > math.MaxInt64 {
+			if v < 0 {
+				return -1, nil
+			}
+			return 0, nil
+		}
+
+		//Round down for negative values as well
+		w := int64(r.intWidth)
+		if v < 0 && v%w != 0 {
+			return v/w - 1, nil
+		}
+
+		return v / w, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		b := value.Uint() / r.intWidth
+		if b > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d is too large for a range index with width %d", value.Uint(), r.intWidth)
+		}
+
+		return int64(b), nil
+
+	case reflect.Float32, reflect.Float64:
+		f := value.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, errors.New("cannot create range index for NaN or infinite values")
+		}
+
+		//float64(math.MaxInt64) rounds up to 2^63, which does not fit in an int64 itself
+		b := math.Floor(f / r.width)
+		if b < math.MinInt64 || b >= math.MaxInt64 {
+			return 0, fmt.Errorf("value %g is too large for a range index with width %g", f, r.width)
+		}
+
+		return int64(b), nil
+
+	default:
+		return 0, fmt.Errorf("expected numeric value for range index, got %s", value.Type().String())
+	}
+}
+
+//Add range token of field to the output object if the field has a range tag
+func (e *encryptionService) addRangeToken(returnObj map[string]interface{}, fieldName string, field *fieldPlan, value reflect.Value) error {
+	if field.rangeErr != nil {
+		return fmt.Errorf("invalid range tag on field %s: %s", field.field.Name, field.rangeErr)
+	}
+	if field.rng == nil {
+		return nil
+	}
+
+	bucket, err := field.rng.bucket(value)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := e.indexTokens(rangeIndexLabel, fieldName, []string{strconv.FormatInt(bucket, 10)})
+	if err != nil {
+		return err
+	}
+
+	returnObj[fieldName+RangeFieldSuffix] = tokens[0]
+
+	return nil
+}
+
+//Get the tokens of all buckets overlapping the range [from, to], documents matching any of them could fall in the range
+func (e *encryptionService) RangeTokens(model interface{}, fieldName string, from interface{}, to interface{}) ([]string, error) {
+	field, err := e.findModelField(model, fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := parseRangeTag(field.Tag.Get("range"), field.Type)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range tag on field %s: %s", field.Name, err)
+	}
+	if idx == nil {
+		return nil, fmt.Errorf("field %s does not have a range index", fieldName)
+	}
+
+	fromVal, toVal := reflect.ValueOf(from), reflect.ValueOf(to)
+	if !fromVal.IsValid() || !toVal.IsValid() || fromVal.Type() != field.Type || toVal.Type() != field.Type {
+		return nil, fmt.Errorf("range bounds should be of type %s", field.Type.String())
+	}
+
+	first, err := idx.bucket(fromVal)
+	if err != nil {
+		return nil, err
+	}
+	last, err := idx.bucket(toVal)
+	if err != nil {
+		return nil, err
+	}
+
+	if first > last {
+		return nil, errors.New("start of range is after end of range")
+	}
+	//The difference of two int64 values always fits in an uint64
+	span := uint64(last) - uint64(first)
+	if span >= RangeMaxTokens {
+		return nil, fmt.Errorf("range spans more than %d buckets, use a smaller range or a bigger bucket width", RangeMaxTokens)
+	}
+
+	terms := make([]string, 0, span+1)
+	for i := uint64(0); i <= span; i++ {
+		terms = append(terms, strconv.FormatInt(first+int64(i), 10))
+	}
+
+	return e.indexTokens(rangeIndexLabel, fieldName, terms)
+}
@@ -0,0 +1,216 @@
+package encryption
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type rangeable struct {
+	Id          string    `bson:"_id" encrypted:"false"`
+	Salary      int       `bson:"salary" range:"width=1000"`
+	DateOfBirth time.Time `bson:"date_of_birth" range:"width=8760h"`
+	Score       float64   `bson:"score"`
+}
+
+func Test_encryptionService_RangeTokens(t *testing.T) {
+	type args struct {
+		fieldName string
+		from      interface{}
+		to        interface{}
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantCount int
+		wantMatch bool
+		wantErr   bool
+	}{
+		{
+			name: "salary within range",
+			args: args{
+				fieldName: "salary",
+				from:      40000,
+				to:        50000,
+			},
+			wantCount: 11,
+			wantMatch: true,
+			wantErr:   false,
+		},
+		{
+			name: "salary outside range",
+			args: args{
+				fieldName: "salary",
+				from:      50000,
+				to:        60000,
+			},
+			wantCount: 11,
+			wantMatch: false,
+			wantErr:   false,
+		},
+		{
+			name: "date of birth within range",
+			args: args{
+				fieldName: "date_of_birth",
+				from:      time.Date(1985, 1, 1, 0, 0, 0, 0, time.UTC),
+				to:        time.Date(1995, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			wantCount: 11,
+			wantMatch: true,
+			wantErr:   false,
+		},
+		{
+			name: "bounds of wrong type",
+			args: args{
+				fieldName: "salary",
+				from:      "40000",
+				to:        "50000",
+			},
+			wantErr: true,
+		},
+		{
+			name: "reversed range",
+			args: args{
+				fieldName: "salary",
+				from:      50000,
+				to:        40000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "field without range index",
+			args: args{
+				fieldName: "score",
+				from:      1.0,
+				to:        2.0,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := encryptionService{
+				key: []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254},
+			}
+
+			encryptedData, err := e.EncryptToInterface(rangeable{
+				Id:          "1",
+				Salary:      45250,
+				DateOfBirth: time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC),
+				Score:       1.5,
+			})
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while encrypting object", err)
+			}
+
+			tokens, err := e.RangeTokens(rangeable{}, tt.args.fieldName, tt.args.from, tt.args.to)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.RangeTokens() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(tokens) != tt.wantCount {
+				t.Errorf("encryptionService.RangeTokens() returned %d tokens, want %d", len(tokens), tt.wantCount)
+			}
+
+			stored := encryptedData[tt.args.fieldName+RangeFieldSuffix]
+			found := false
+			for _, token := range tokens {
+				if token == stored {
+					found = true
+				}
+			}
+			if found != tt.wantMatch {
+				t.Errorf("stored token matched = %v, want %v", found, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func Test_encryptionService_RangeTokens_ExtremeBounds(t *testing.T) {
+	type extremes struct {
+		Signed   int64  `bson:"signed" range:"width=1"`
+		Unsigned uint64 `bson:"unsigned" range:"width=1"`
+	}
+
+	tests := []struct {
+		name      string
+		fieldName string
+		from      interface{}
+		to        interface{}
+		wantCount int
+		wantErr   bool
+	}{
+		{name: "smallest int64 to zero", fieldName: "signed", from: int64(math.MinInt64), to: int64(0), wantErr: true},
+		{name: "whole int64 range", fieldName: "signed", from: int64(math.MinInt64), to: int64(math.MaxInt64), wantErr: true},
+		{name: "largest int64 values", fieldName: "signed", from: int64(math.MaxInt64 - 2), to: int64(math.MaxInt64), wantCount: 3},
+		{name: "smallest int64 values", fieldName: "signed", from: int64(math.MinInt64), to: int64(math.MinInt64 + 1), wantCount: 2},
+		{name: "whole uint64 range", fieldName: "unsigned", from: uint64(0), to: uint64(math.MaxUint64), wantErr: true},
+		{name: "uint64 up to largest bucket", fieldName: "unsigned", from: uint64(0), to: uint64(math.MaxInt64), wantErr: true},
+		{name: "largest uint64 bucket", fieldName: "unsigned", from: uint64(math.MaxInt64 - 1), to: uint64(math.MaxInt64), wantCount: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+
+			tokens, err := e.(BlindIndexer).RangeTokens(extremes{}, tt.fieldName, tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("encryptionService.RangeTokens() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(tokens) != tt.wantCount {
+				t.Errorf("encryptionService.RangeTokens() returned %d tokens, want %d", len(tokens), tt.wantCount)
+			}
+		})
+	}
+}
+
+func Test_rangeIndex_bucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		value   interface{}
+		want    int64
+		wantErr bool
+	}{
+		{name: "int", tag: "width=1000", value: 45250, want: 45},
+		{name: "negative int", tag: "width=1000", value: -45250, want: -46},
+		{name: "int64 above 2^53", tag: "width=10", value: int64(9007199254740999), want: 900719925474099},
+		{name: "next int64 above 2^53", tag: "width=10", value: int64(9007199254741000), want: 900719925474100},
+		{name: "smallest int64", tag: "width=10", value: int64(math.MinInt64), want: math.MinInt64/10 - 1},
+		{name: "width bigger than int64", tag: "width=18446744073709551615", value: int64(-1), want: -1},
+		{name: "largest uint64", tag: "width=2", value: uint64(math.MaxUint64), want: math.MaxInt64},
+		{name: "uint64 overflowing bucket", tag: "width=1", value: uint64(math.MaxUint64), wantErr: true},
+		{name: "float", tag: "width=0.5", value: 1.75, want: 3},
+		{name: "float overflowing bucket", tag: "width=0.5", value: 1e300, wantErr: true},
+		{name: "NaN", tag: "width=1", value: math.NaN(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := reflect.ValueOf(tt.value)
+			idx, err := parseRangeTag(tt.tag, value.Type())
+			if err != nil {
+				t.Fatalf("parseRangeTag() error = %v", err)
+			}
+
+			got, err := idx.bucket(value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rangeIndex.bucket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("rangeIndex.bucket() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseRangeTag_Integer(t *testing.T) {
+	for _, tag := range []string{"width=0.5", "width=0", "width=-10", "width=1e3"} {
+		if _, err := parseRangeTag(tag, reflect.TypeOf(0)); err == nil {
+			t.Errorf("parseRangeTag(%q) of int field should fail", tag)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//Define symmetric recipient constants
+const (
+	stanzaSymmetric byte = 4
+	symWrapLabel         = "globe-protocol/encryption wrap key"
+	symKeyIDLabel        = "globe-protocol/encryption key id"
+)
+
+//Recipient an envelope can be encrypted for, either a symmetric key or a public key
+type Recipient struct {
+	r recipient
+}
+
+//Create a recipient for a service created with NewEncryptionService using the same key
+func NewSymmetricRecipient(key []byte) (Recipient, error) {
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return Recipient{}, fmt.Errorf("symmetric key should be 16, 24 or 32 bytes, got %d", len(key))
+	}
+
+	return Recipient{r: &symmetricIdentity{key: key}}, nil
+}
+
+//Create a recipient for a service created with NewPrivateKeyEncryptionService using the matching private key
+func NewPublicKeyRecipient(key crypto.PublicKey) (Recipient, error) {
+	r, err := newRecipient(key)
+	if err != nil {
+		return Recipient{}, err
+	}
+
+	return Recipient{r: r}, nil
+}
+
+//Derive a key for the given purpose from another key
+func deriveKeyFrom(key []byte, label string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(label))
+
+	return mac.Sum(nil)
+}
+
+type symmetricIdentity struct {
+	key []byte
+}
+
+func (s *symmetricIdentity) stanzaType() byte {
+	return stanzaSymmetric
+}
+
+func (s *symmetricIdentity) keyID() []byte {
+	return deriveKeyFrom(s.key, symKeyIDLabel)[:keyIDSize]
+}
+
+func (s *symmetricIdentity) wrap(cek []byte) ([]byte, error) {
+	aesGCM, err := newGCM(deriveKeyFrom(s.key, symWrapLabel))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aesGCM.Seal(nonce, nonce, cek, []byte(EnvelopeMagic)), nil
+}
+
+func (s *symmetricIdentity) unwrap(body []byte) ([]byte, error) {
+	aesGCM, err := newGCM(deriveKeyFrom(s.key, symWrapLabel))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) < aesGCM.NonceSize() {
+		return nil, errors.New("stanza is too short")
+	}
+
+	return aesGCM.Open(nil, body[:aesGCM.NonceSize()], body[aesGCM.NonceSize():], []byte(EnvelopeMagic))
+}
+
+//Encrypt plaintext once and wrap the content key for every recipient so that each of them can decrypt it with DecryptEnvelope
+func EncryptFor(recipients []Recipient, plaintext []byte) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("at least one recipient is required")
+	}
+
+	rs := make([]recipient, 0, len(recipients))
+	for _, r := range recipients {
+		if r.r == nil {
+			return nil, errors.New("recipient was not created with NewSymmetricRecipient or NewPublicKeyRecipient")
+		}
+		rs = append(rs, r.r)
+	}
+
+	return sealEnvelope(rs, plaintext)
+}
+
+//Get the identity used to open envelopes, the private key if the service has one and otherwise the symmetric key
+func (e *encryptionService) envelopeIdentity() (identity, error) {
+	if e.identity != nil {
+		return e.identity, nil
+	}
+	if len(e.key) > 0 {
+		return &symmetricIdentity{key: e.key}, nil
+	}
+
+	return nil, errors.New("service was created without a private key and cannot decrypt")
+}
+
+//Decrypt an envelope created by EncryptFor that has a recipient for the key of this service
+func (e *encryptionService) DecryptEnvelope(ciphertext []byte) ([]byte, error) {
+	id, err := e.envelopeIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	return env.open(id)
+}
+
+//Wrap the content key of an envelope the service can decrypt for an extra recipient, the body is not encrypted again
+func (e *encryptionService) AddRecipient(ciphertext []byte, r Recipient) ([]byte, error) {
+	if r.r == nil {
+		return nil, errors.New("recipient was not created with NewSymmetricRecipient or NewPublicKeyRecipient")
+	}
+
+	id, err := e.envelopeIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	if env.find(r.r) != -1 {
+		return nil, errors.New("envelope already has this recipient")
+	}
+
+	cek, err := env.contentKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	//Make sure the content key actually decrypts the body before handing it to someone else
+	if _, err := env.openWithKey(cek); err != nil {
+		return nil, err
+	}
+
+	body, err := r.r.wrap(cek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap content key, the following error occured: %s", err)
+	}
+	env.stanzas = append(env.stanzas, stanza{typ: r.r.stanzaType(), keyID: r.r.keyID(), body: body})
+
+	return env.marshal()
+}
+
+//Remove the wrapped content key of a recipient from an envelope, the recipient can no longer decrypt this copy of the envelope
+func RevokeRecipient(ciphertext []byte, r Recipient) ([]byte, error) {
+	if r.r == nil {
+		return nil, errors.New("recipient was not created with NewSymmetricRecipient or NewPublicKeyRecipient")
+	}
+
+	env, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	i := env.find(r.r)
+	if i == -1 {
+		return nil, errors.New("envelope does not have this recipient")
+	}
+	if len(env.stanzas) == 1 {
+		return nil, errors.New("cannot revoke the last recipient of an envelope")
+	}
+
+	env.stanzas = append(env.stanzas[:i], env.stanzas[i+1:]...)
+
+	return env.marshal()
+}
+
+//Get index of the stanza of the recipient, -1 if the envelope has no stanza for it
+func (env *envelope) find(r recipient) int {
+	keyID := r.keyID()
+	for i, s := range env.stanzas {
+		if s.typ == r.stanzaType() && bytes.Equal(s.keyID, keyID) {
+			return i
+		}
+	}
+
+	return -1
+}
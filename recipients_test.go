@@ -0,0 +1,148 @@
+package encryption
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"testing"
+)
+
+func Test_EncryptFor(t *testing.T) {
+	keyA := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+	keyB := []byte{12, 21, 21, 45, 52, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 1}
+	keyC := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serviceA := NewEncryptionService(keyA)
+	serviceB := NewEncryptionService(keyB)
+	serviceC := NewEncryptionService(keyC)
+	servicePK, err := NewPrivateKeyEncryptionService(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipientA, err := NewSymmetricRecipient(keyA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipientB, err := NewSymmetricRecipient(keyB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipientC, err := NewSymmetricRecipient(keyC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipientPK, err := NewPublicKeyRecipient(privateKey.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := []byte("record readable by several services")
+	envelope, err := EncryptFor([]Recipient{recipientA, recipientB, recipientPK}, plain)
+	if err != nil {
+		t.Fatalf("Did not expect error: %s while encrypting", err)
+	}
+
+	tests := []struct {
+		name     string
+		service  EncryptionService
+		envelope func() ([]byte, error)
+		wantErr  bool
+	}{
+		{
+			name:     "first symmetric recipient",
+			service:  serviceA,
+			envelope: func() ([]byte, error) { return envelope, nil },
+			wantErr:  false,
+		},
+		{
+			name:     "second symmetric recipient",
+			service:  serviceB,
+			envelope: func() ([]byte, error) { return envelope, nil },
+			wantErr:  false,
+		},
+		{
+			name:     "public key recipient",
+			service:  servicePK,
+			envelope: func() ([]byte, error) { return envelope, nil },
+			wantErr:  false,
+		},
+		{
+			name:     "not a recipient",
+			service:  serviceC,
+			envelope: func() ([]byte, error) { return envelope, nil },
+			wantErr:  true,
+		},
+		{
+			name:     "added recipient",
+			service:  serviceC,
+			envelope: func() ([]byte, error) { return serviceA.(EnvelopeService).AddRecipient(envelope, recipientC) },
+			wantErr:  false,
+		},
+		{
+			name:     "revoked recipient",
+			service:  serviceB,
+			envelope: func() ([]byte, error) { return RevokeRecipient(envelope, recipientB) },
+			wantErr:  true,
+		},
+		{
+			name:     "remaining recipient after revoke",
+			service:  serviceA,
+			envelope: func() ([]byte, error) { return RevokeRecipient(envelope, recipientB) },
+			wantErr:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.envelope()
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while preparing envelope", err)
+			}
+
+			got, err := tt.service.(EnvelopeService).DecryptEnvelope(b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.DecryptEnvelope() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && string(got) != string(plain) {
+				t.Errorf("encryptionService.DecryptEnvelope() = %s, want %s", got, plain)
+			}
+		})
+	}
+}
+
+func Test_encryptionService_AddRecipient_Errors(t *testing.T) {
+	keyA := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+	keyC := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	recipientA, err := NewSymmetricRecipient(keyA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipientC, err := NewSymmetricRecipient(keyC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, err := EncryptFor([]Recipient{recipientA}, []byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewEncryptionService(keyC).(EnvelopeService).AddRecipient(envelope, recipientC); err == nil {
+		t.Errorf("expected error when adding recipient without access to the envelope")
+	}
+	if _, err := NewEncryptionService(keyA).(EnvelopeService).AddRecipient(envelope, recipientA); err == nil {
+		t.Errorf("expected error when adding existing recipient")
+	}
+	if _, err := RevokeRecipient(envelope, recipientA); err == nil {
+		t.Errorf("expected error when revoking last recipient")
+	}
+	if _, err := NewSymmetricRecipient([]byte("short")); err == nil {
+		t.Errorf("expected error for invalid symmetric key size")
+	}
+}
@@ -0,0 +1,149 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+//Type names Decrypt can convert decrypted strings to
+var supportedTypeNames = map[string]bool{
+	Int8: true, Uint8: true, Byte: true, Int16: true, Uint16: true, Int32: true, Uint32: true, Int64: true, Uint64: true,
+	Int: true, Uint: true, Uintptr: true, Float32: true, Float64: true, String: true, StringArr: true, Time: true, Bool: true,
+}
+
+//Validate model types up front so that mistakes in tags or field types do not surface in the struct functions at runtime
+func (e *encryptionService) Register(models ...interface{}) error {
+	var errs []error
+	for _, model := range models {
+		if err := e.validateModel(reflect.TypeOf(model)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+//Register model types and panic if any of them is invalid, meant to be called from init
+func (e *encryptionService) MustRegister(models ...interface{}) {
+	if err := e.Register(models...); err != nil {
+		panic(fmt.Sprintf("encryption: invalid model, the following error occured: %s", err))
+	}
+}
+
+//Check every field of the model and return all problems at once
+func (e *encryptionService) validateModel(t reflect.Type) error {
+	if t == nil {
+		return errors.New("model cannot be nil")
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("model %s is not a struct", t)
+	}
+
+	var errs []error
+	problem := func(f *fieldPlan, format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s.%s: %s", t.Name(), f.field.Name, fmt.Sprintf(format, a...)))
+	}
+
+	//Names used by EncryptToInterface and EncryptToJSON, generated index fields can collide as well
+	mappings := []*modelMapping{
+		{function: "EncryptToInterface", tagNames: interfaceTagNames, names: map[string]string{}},
+		{function: "EncryptToJSON", tagNames: jsonTagNames, names: map[string]string{}},
+	}
+	if e.documentMAC {
+		for _, mapping := range mappings {
+			mapping.names[DocumentMACField] = "the document MAC"
+		}
+	}
+
+	plan := planFor(t)
+	for i := range plan.fields {
+		f := &plan.fields[i]
+
+		if !f.field.IsExported() {
+			problem(f, "field is not exported")
+			continue
+		}
+		//Decrypt converts plain fields from strings as well
+		if !supportedTypeNames[f.typeName] {
+			problem(f, "type %s is not supported", f.typeName)
+		}
+
+		if f.optionsErr != nil {
+			problem(f, "invalid encrypted tag: %s", f.optionsErr)
+		} else if f.options.sealsOwn() && len(e.key) == 0 {
+			problem(f, "encrypted tag options key, mode and aad require a service created with a symmetric key")
+		}
+		if f.searchErr != nil {
+			problem(f, "invalid search tag: %s", f.searchErr)
+		}
+		if f.rangeErr != nil {
+			problem(f, "invalid range tag: %s", f.rangeErr)
+		}
+
+		//Fields with a bson tag collide in both mappings, the collision is reported once
+		collisions := map[string]bool{}
+		for _, mapping := range mappings {
+			mapping.add(f, collisions, func(format string, a ...interface{}) error {
+				return fmt.Errorf("%s.%s: %s", t.Name(), f.field.Name, fmt.Sprintf(format, a...))
+			})
+		}
+	}
+
+	//A model only has to work with one of the functions, like a struct with json tags that is only used with EncryptToJSON
+	var complete []*modelMapping
+	for _, mapping := range mappings {
+		if len(mapping.missing) == 0 {
+			complete = append(complete, mapping)
+		}
+	}
+	if len(complete) == 0 {
+		complete = mappings
+	}
+	for _, mapping := range complete {
+		errs = append(errs, mapping.missing...)
+		errs = append(errs, mapping.collisions...)
+	}
+
+	return errors.Join(errs...)
+}
+
+//Output names of a model for one of the struct functions, with the problems found while collecting them
+type modelMapping struct {
+	function   string
+	tagNames   []string
+	names      map[string]string
+	missing    []error
+	collisions []error
+}
+
+//Add the output names of a field, problem formats an error about the field
+func (m *modelMapping) add(f *fieldPlan, collisions map[string]bool, problem func(format string, a ...interface{}) error) {
+	name, err := f.name(m.tagNames)
+	if err != nil {
+		m.missing = append(m.missing, problem("needs a %s or %s tag to be used with %s", m.tagNames[0], m.tagNames[1], m.function))
+		return
+	}
+
+	outputNames := []string{name}
+	if f.search != nil {
+		outputNames = append(outputNames, name+SearchFieldSuffix)
+	}
+	if f.rng != nil {
+		outputNames = append(outputNames, name+RangeFieldSuffix)
+	}
+	for _, outputName := range outputNames {
+		if other, ok := m.names[outputName]; ok {
+			collision := outputName + "\x00" + other
+			if !collisions[collision] {
+				m.collisions = append(m.collisions, problem("name %q is already used by %s", outputName, other))
+				collisions[collision] = true
+			}
+			continue
+		}
+		m.names[outputName] = "field " + f.field.Name
+	}
+}
@@ -0,0 +1,172 @@
+package encryption
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+type registerModel struct {
+	Id       string   `bson:"_id" encrypted:"false"`
+	TenantId string   `bson:"tenant_id" encrypted:"false"`
+	Email    string   `bson:"email" encrypted:"true,key=pii,mode=deterministic,aad=tenant_id"`
+	Name     string   `ename:"name" json:"name" search:"prefix"`
+	Tags     []string `bson:"tags" encrypted:",omitempty"`
+}
+
+func Test_encryptionService_Register(t *testing.T) {
+	type missingTags struct {
+		Name  string `bson:"name"`
+		Email string `ename:"email"`
+		Phone string `json:"phone"`
+		Age   int
+	}
+	type invalidTags struct {
+		Name  string `bson:"name" encrypted:"true,compress" search:"suffix"`
+		Age   int    `bson:"age" range:"width=0"`
+		Notes string `bson:"notes" encrypted:"true,aad=missing"`
+	}
+	type duplicates struct {
+		Name       string `bson:"name" search:"prefix"`
+		Alias      string `bson:"name"`
+		NameSearch string `ename:"name_search" json:"search"`
+	}
+	type jsonOnly struct {
+		Id    string `json:"id" encrypted:"false"`
+		Email string `json:"email" search:"prefix"`
+	}
+	type enameOnly struct {
+		Id    string `ename:"id" encrypted:"false"`
+		Email string `ename:"email"`
+	}
+	type jsonOnlyDuplicates struct {
+		Email       string `json:"email" search:"prefix"`
+		EmailSearch string `json:"email_search"`
+	}
+	type unsupported struct {
+		Ports  []int             `bson:"ports"`
+		Meta   map[string]string `bson:"meta" encrypted:"false"`
+		hidden string
+	}
+
+	tests := []struct {
+		name    string
+		models  []interface{}
+		wantErr []string
+	}{
+		{name: "valid model", models: []interface{}{registerModel{}, &registerModel{}}},
+		{
+			name:   "missing tags",
+			models: []interface{}{missingTags{}},
+			wantErr: []string{
+				"missingTags.Email: needs a bson or json tag to be used with EncryptToJSON",
+				"missingTags.Phone: needs a bson or ename tag to be used with EncryptToInterface",
+				"missingTags.Age: needs a bson or ename tag to be used with EncryptToInterface",
+				"missingTags.Age: needs a bson or json tag to be used with EncryptToJSON",
+			},
+		},
+		{name: "model only used with EncryptToJSON", models: []interface{}{jsonOnly{}}},
+		{name: "model only used with EncryptToInterface", models: []interface{}{enameOnly{}}},
+		{
+			name:    "duplicate names of the only usable function",
+			models:  []interface{}{jsonOnlyDuplicates{}},
+			wantErr: []string{"jsonOnlyDuplicates.EmailSearch: name \"email_search\" is already used by field Email"},
+		},
+		{
+			name:   "invalid tags",
+			models: []interface{}{invalidTags{}},
+			wantErr: []string{
+				"invalidTags.Name: invalid encrypted tag: compress is not a supported option",
+				"invalidTags.Name: invalid search tag: suffix is not a supported search mode",
+				"invalidTags.Age: invalid range tag",
+				"invalidTags.Notes: invalid encrypted tag: aad field missing does not exist",
+			},
+		},
+		{
+			name:   "duplicate names",
+			models: []interface{}{duplicates{}},
+			wantErr: []string{
+				"duplicates.Alias: name \"name\" is already used by field Name",
+				"duplicates.NameSearch: name \"name_search\" is already used by field Name",
+			},
+		},
+		{
+			name:   "unsupported types",
+			models: []interface{}{unsupported{}},
+			wantErr: []string{
+				"unsupported.Ports: type []int is not supported",
+				"unsupported.Meta: type map[string]string is not supported",
+				"unsupported.hidden: field is not exported",
+			},
+		},
+		{
+			name:    "not a struct",
+			models:  []interface{}{registerModel{}, "model"},
+			wantErr: []string{"model string is not a struct"},
+		},
+		{
+			name:    "nil model",
+			models:  []interface{}{nil},
+			wantErr: []string{"model cannot be nil"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+
+			err := e.(ModelRegistry).Register(tt.models...)
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Fatalf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Register() error = %v, want it to contain %q", err, want)
+				}
+			}
+			if err != nil && strings.Count(err.Error(), "\n")+1 != len(tt.wantErr) {
+				t.Errorf("Register() error = %v, want %d problems", err, len(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_encryptionService_Register_Service(t *testing.T) {
+	type macModel struct {
+		Name string `bson:"name"`
+		MAC  string `bson:"_mac"`
+	}
+
+	withMAC, err := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef")).(DocumentAuthenticator).WithDocumentMAC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := withMAC.(ModelRegistry).Register(macModel{}); err == nil || !strings.Contains(err.Error(), "name \"_mac\" is already used by the document MAC") {
+		t.Errorf("Register() error = %v, want reserved name error", err)
+	}
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := NewPublicKeyEncryptionService(key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publicKey.(ModelRegistry).Register(registerModel{}); err == nil || !strings.Contains(err.Error(), "registerModel.Email: encrypted tag options key, mode and aad require a service created with a symmetric key") {
+		t.Errorf("Register() error = %v, want symmetric key error", err)
+	}
+}
+
+func Test_encryptionService_MustRegister(t *testing.T) {
+	e := NewEncryptionService([]byte("0123456789abcdef0123456789abcdef"))
+
+	e.(ModelRegistry).MustRegister(registerModel{})
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustRegister() of invalid model should panic")
+		}
+	}()
+	e.(ModelRegistry).MustRegister(struct{ Name string }{})
+}
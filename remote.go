@@ -0,0 +1,151 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+//Client for the encryption server, it implements EncryptionService and the other interfaces of service.go so that it can replace a local service
+type RemoteService struct {
+	*encryptionService
+	remote *remoteClient
+}
+
+type remoteClient struct {
+	baseURL    string
+	keyName    string
+	token      string
+	httpClient *http.Client
+}
+
+//Create client for the given key of the encryption server at baseURL, http.DefaultClient is used when httpClient is nil
+func NewRemoteService(baseURL string, keyName string, token string, httpClient *http.Client) *RemoteService {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	remote := &remoteClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		keyName:    keyName,
+		token:      token,
+		httpClient: httpClient,
+	}
+
+	return &RemoteService{
+		encryptionService: &encryptionService{remote: remote},
+		remote:            remote,
+	}
+}
+
+//Decrypt ciphertext on the server and encrypt it again with the current key version without revealing the plaintext
+func (r *RemoteService) Rewrap(ciphertext []byte) ([]byte, error) {
+	resp, err := r.remote.call("rewrap", serverRequest{Ciphertext: remoteCiphertext(ciphertext)})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(resp.Ciphertext), nil
+}
+
+//Add a new key version on the server and get its version number
+func (r *RemoteService) Rotate() (uint32, error) {
+	resp, err := r.remote.call("rotate", serverRequest{})
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Version, nil
+}
+
+//Generate a random 32 byte data key, get it in plain to use locally and encrypted to store next to the data
+func (r *RemoteService) GenerateDataKey() ([]byte, []byte, error) {
+	resp, err := r.remote.call("datakey", serverRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp.Plaintext, []byte(resp.Ciphertext), nil
+}
+
+func (c *remoteClient) call(op string, req serverRequest) (*serverResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, c.baseURL+ServerPathPrefix+url.PathEscape(c.keyName)+"/"+op, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.token)
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call encryption server, the following error occured: %s", err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp serverResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response of encryption server with status %d, the following error occured: %s", httpResp.StatusCode, err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("encryption server returned status %d: %s", httpResp.StatusCode, resp.Error)
+	}
+
+	return &resp, nil
+}
+
+//Encrypt on the server, the result is a text ciphertext so that the key version is kept
+func (c *remoteClient) seal(plain []byte) ([]byte, error) {
+	resp, err := c.call("encrypt", serverRequest{Plaintext: plain})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(resp.Ciphertext), nil
+}
+
+func (c *remoteClient) open(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	resp, err := c.call("decrypt", serverRequest{Ciphertext: remoteCiphertext(ciphertext)})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Plaintext, nil
+}
+
+func (c *remoteClient) indexTokens(label string, fieldName string, terms []string) ([]string, error) {
+	for index, l := range serverIndexLabels {
+		if l == label {
+			resp, err := c.call("blind-index", serverRequest{Index: index, Field: fieldName, Terms: terms})
+			if err != nil {
+				return nil, err
+			}
+
+			return resp.Tokens, nil
+		}
+	}
+
+	return nil, fmt.Errorf("index %q is not supported by the encryption server", label)
+}
+
+//Text ciphertexts are sent as they are, binary ciphertexts as standard base64
+func remoteCiphertext(b []byte) string {
+	if bytes.HasPrefix(b, []byte(TextPrefix)) {
+		return string(b)
+	}
+
+	return base64.StdEncoding.EncodeToString(b)
+}
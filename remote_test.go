@@ -0,0 +1,103 @@
+package encryption
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_RemoteService(t *testing.T) {
+	srv, key := newTestServer(t)
+
+	remote := NewRemoteService(srv.URL, "payments", "payments-token", nil)
+	local := NewEncryptionService(key.Keyring.Keys[1])
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, e EncryptionService)
+	}{
+		{
+			name: "string",
+			run: func(t *testing.T, e EncryptionService) {
+				b, err := e.EncryptStr("123Test")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				//Both services read the ciphertext of the other
+				for _, s := range []EncryptionService{remote, local} {
+					got, err := s.DecryptStr(b)
+					if err != nil || got != "123Test" {
+						t.Errorf("DecryptStr() = %v, %v", got, err)
+					}
+				}
+			},
+		},
+		{
+			name: "struct",
+			run: func(t *testing.T, e EncryptionService) {
+				data := stringfloatbool{String: "123Test", Float64: 64.64, Bool: true, StringArr: []string{"a", "b"}, EmptyVal: "x"}
+				encrypted, err := e.EncryptToInterface(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				encryptedObj := stringfloatboolEnc{
+					String:    encrypted["String"].(string),
+					Float64:   encrypted["Float64"].([]byte),
+					Bool:      encrypted["Bool"].([]byte),
+					StringArr: encrypted["StringArr"].([]byte),
+					EmptyVal:  encrypted["EmptyVal"].([]byte),
+				}
+
+				for _, s := range []EncryptionService{remote, local} {
+					got, err := s.Decrypt(encryptedObj, stringfloatbool{})
+					if err != nil || !reflect.DeepEqual(got, &data) {
+						t.Errorf("Decrypt() = %+v, %v", got, err)
+					}
+				}
+			},
+		},
+		{
+			name: "search tokens",
+			run: func(t *testing.T, e EncryptionService) {
+				got, err := e.(BlindIndexer).SearchTokens(searchable{}, "name", "joh")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want, err := local.(BlindIndexer).SearchTokens(searchable{}, "name", "joh")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("SearchTokens() = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name: "expiring token",
+			run: func(t *testing.T, e EncryptionService) {
+				token, err := e.(ExpiringEncrypter).EncryptStrWithTTL("reset", time.Hour)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := e.(ExpiringEncrypter).DecryptStrWithTTL(token)
+				if err != nil || got != "reset" {
+					t.Errorf("DecryptStrWithTTL() = %v, %v", got, err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, remote)
+		})
+	}
+
+	wrongToken := NewRemoteService(srv.URL, "payments", "users-token", nil)
+	if _, err := wrongToken.EncryptStr("123Test"); err == nil {
+		t.Errorf("expected error when using the token of another key")
+	}
+}
@@ -0,0 +1,229 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+//Define encryption server constants
+const (
+	ServerPathPrefix     = "/v1/keys/"
+	ServerMaxBodySize    = 32 << 20
+	serverDataKeySize    = 32
+	serverIndexSearch    = "search"
+	serverIndexRange     = "range"
+	serverRotatedKeySize = 32
+)
+
+//Labels of the blind indexes that can be computed by the server
+var serverIndexLabels = map[string]string{
+	serverIndexSearch: searchIndexLabel,
+	serverIndexRange:  rangeIndexLabel,
+}
+
+//Key served by the encryption server
+type ServerKey struct {
+	Keyring Keyring
+	//Tokens that grant access to this key
+	Tokens []string
+	//Called with the new keyring before a rotation takes effect so that it can be persisted, the rotation fails when it returns an error
+	OnRotate func(Keyring) error
+}
+
+type serverRequest struct {
+	Plaintext  []byte   `json:"plaintext,omitempty"`
+	Ciphertext string   `json:"ciphertext,omitempty"`
+	Index      string   `json:"index,omitempty"`
+	Field      string   `json:"field,omitempty"`
+	Terms      []string `json:"terms,omitempty"`
+}
+
+type serverResponse struct {
+	Plaintext  []byte   `json:"plaintext,omitempty"`
+	Ciphertext string   `json:"ciphertext,omitempty"`
+	Version    uint32   `json:"version,omitempty"`
+	Tokens     []string `json:"tokens,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+type server struct {
+	mu       sync.RWMutex
+	keys     map[string]*ServerKey
+	services map[string]*encryptionService
+}
+
+//Create HTTP handler serving encrypt, decrypt, rewrap, rotate, blind-index and datakey operations for the given keys
+func NewServer(keys map[string]*ServerKey) (http.Handler, error) {
+	s := &server{
+		keys:     make(map[string]*ServerKey, len(keys)),
+		services: make(map[string]*encryptionService, len(keys)),
+	}
+
+	for name, key := range keys {
+		if name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("key name %q is not valid", name)
+		}
+		if len(key.Tokens) == 0 {
+			return nil, fmt.Errorf("key %s has no access tokens", name)
+		}
+
+		service, err := NewKeyringEncryptionService(key.Keyring)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s, the following error occured: %s", name, err)
+		}
+
+		s.keys[name] = key
+		s.services[name] = service.(*encryptionService)
+	}
+
+	return s, nil
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeServerResponse(w, http.StatusMethodNotAllowed, serverResponse{Error: "only POST is supported"})
+		return
+	}
+
+	name, op, found := strings.Cut(strings.TrimPrefix(r.URL.Path, ServerPathPrefix), "/")
+	if !strings.HasPrefix(r.URL.Path, ServerPathPrefix) || !found {
+		writeServerResponse(w, http.StatusNotFound, serverResponse{Error: "unknown path"})
+		return
+	}
+
+	//Unknown keys and invalid tokens are reported the same way so that key names cannot be discovered
+	s.mu.RLock()
+	key, service := s.keys[name], s.services[name]
+	s.mu.RUnlock()
+	if key == nil || !validServerToken(key.Tokens, r.Header.Get("Authorization")) {
+		writeServerResponse(w, http.StatusForbidden, serverResponse{Error: "access denied"})
+		return
+	}
+
+	var req serverRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, ServerMaxBodySize)).Decode(&req); err != nil && err != io.EOF {
+		writeServerResponse(w, http.StatusBadRequest, serverResponse{Error: "request body is not valid JSON"})
+		return
+	}
+
+	var resp serverResponse
+	var err error
+	switch op {
+	case "encrypt":
+		resp.Ciphertext, err = service.EncryptStrText(string(req.Plaintext))
+	case "decrypt":
+		resp.Plaintext, err = serverDecrypt(service, req.Ciphertext)
+	case "rewrap":
+		var plain []byte
+		if plain, err = serverDecrypt(service, req.Ciphertext); err == nil {
+			resp.Ciphertext, err = service.EncryptStrText(string(plain))
+		}
+	case "rotate":
+		resp.Version, err = s.rotate(name)
+	case "blind-index":
+		label, ok := serverIndexLabels[req.Index]
+		if !ok {
+			err = fmt.Errorf("index %q is not supported", req.Index)
+			break
+		}
+		resp.Tokens, err = service.indexTokens(label, req.Field, req.Terms)
+	case "datakey":
+		resp.Plaintext = make([]byte, serverDataKeySize)
+		if _, err = io.ReadFull(rand.Reader, resp.Plaintext); err == nil {
+			resp.Ciphertext, err = service.EncryptStrText(string(resp.Plaintext))
+		}
+	default:
+		writeServerResponse(w, http.StatusNotFound, serverResponse{Error: fmt.Sprintf("operation %q is not supported", op)})
+		return
+	}
+
+	if err != nil {
+		writeServerResponse(w, http.StatusBadRequest, serverResponse{Error: err.Error()})
+		return
+	}
+
+	writeServerResponse(w, http.StatusOK, resp)
+}
+
+//Add a new random key version to the keyring of the key and make it the current version
+func (s *server) rotate(name string) (uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := s.keys[name]
+	//Blind index tokens keep being derived from the same version, otherwise stored tokens would stop matching
+	keyring := Keyring{Keys: make(map[uint32][]byte, len(key.Keyring.Keys)+1), Derive: key.Keyring.DeriveVersion()}
+	for version, k := range key.Keyring.Keys {
+		keyring.Keys[version] = k
+		if version > keyring.Current {
+			keyring.Current = version
+		}
+	}
+
+	keyring.Current++
+	keyring.Keys[keyring.Current] = make([]byte, serverRotatedKeySize)
+	if _, err := io.ReadFull(rand.Reader, keyring.Keys[keyring.Current]); err != nil {
+		return 0, err
+	}
+
+	service, err := NewKeyringEncryptionService(keyring)
+	if err != nil {
+		return 0, err
+	}
+
+	if key.OnRotate != nil {
+		if err := key.OnRotate(keyring); err != nil {
+			return 0, fmt.Errorf("failed to persist rotated keyring, the following error occured: %s", err)
+		}
+	}
+
+	key.Keyring = keyring
+	s.services[name] = service.(*encryptionService)
+
+	return keyring.Current, nil
+}
+
+//Decrypt ciphertext sent as text ciphertext or as standard base64 of a binary ciphertext
+func serverDecrypt(service *encryptionService, ciphertext string) ([]byte, error) {
+	if ciphertext == "" {
+		return nil, errors.New("ciphertext is required")
+	}
+
+	b := []byte(ciphertext)
+	if !strings.HasPrefix(ciphertext, TextPrefix) {
+		var err error
+		if b, err = base64.StdEncoding.DecodeString(ciphertext); err != nil {
+			return nil, errors.New("ciphertext should be a text ciphertext or standard base64")
+		}
+	}
+
+	return service.DecryptByt(b)
+}
+
+func validServerToken(tokens []string, authorization string) bool {
+	token, found := strings.CutPrefix(authorization, "Bearer ")
+	if !found || token == "" {
+		return false
+	}
+
+	valid := 0
+	for _, t := range tokens {
+		valid |= subtle.ConstantTimeCompare([]byte(t), []byte(token))
+	}
+
+	return valid == 1
+}
+
+func writeServerResponse(w http.ResponseWriter, status int, resp serverResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,235 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *ServerKey) {
+	key := &ServerKey{
+		Keyring: Keyring{Current: 1, Keys: map[uint32][]byte{1: []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}}},
+		Tokens:  []string{"payments-token"},
+	}
+
+	handler, err := NewServer(map[string]*ServerKey{
+		"payments": key,
+		"users":    {Keyring: Keyring{Current: 1, Keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}}, Tokens: []string{"users-token"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	return srv, key
+}
+
+func Test_NewServer(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "encrypt",
+			method:     http.MethodPost,
+			path:       "/v1/keys/payments/encrypt",
+			token:      "payments-token",
+			body:       `{"plaintext":"c2VjcmV0"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "token of other key",
+			method:     http.MethodPost,
+			path:       "/v1/keys/payments/encrypt",
+			token:      "users-token",
+			body:       `{"plaintext":"c2VjcmV0"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "missing token",
+			method:     http.MethodPost,
+			path:       "/v1/keys/payments/encrypt",
+			body:       `{"plaintext":"c2VjcmV0"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "unknown key",
+			method:     http.MethodPost,
+			path:       "/v1/keys/orders/encrypt",
+			token:      "payments-token",
+			body:       `{"plaintext":"c2VjcmV0"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "unknown operation",
+			method:     http.MethodPost,
+			path:       "/v1/keys/payments/sign",
+			token:      "payments-token",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "GET",
+			method:     http.MethodGet,
+			path:       "/v1/keys/payments/encrypt",
+			token:      "payments-token",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "invalid ciphertext",
+			method:     http.MethodPost,
+			path:       "/v1/keys/payments/decrypt",
+			token:      "payments-token",
+			body:       `{"ciphertext":"globe:v1:AAAA"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unsupported index",
+			method:     http.MethodPost,
+			path:       "/v1/keys/payments/blind-index",
+			token:      "payments-token",
+			body:       `{"index":"bloom","field":"name","terms":["jo"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_server_rotate(t *testing.T) {
+	srv, key := newTestServer(t)
+
+	var persisted Keyring
+	key.OnRotate = func(k Keyring) error {
+		persisted = k
+		return nil
+	}
+
+	client := NewRemoteService(srv.URL, "payments", "payments-token", nil)
+	old, err := client.EncryptByt([]byte("card"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type person struct {
+		Name string `bson:"name" search:"prefix"`
+	}
+	tokens, err := client.SearchTokens(person{}, "name", "card")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := client.Rotate()
+	if err != nil {
+		t.Fatalf("RemoteService.Rotate() error = %v", err)
+	}
+	if version != 2 || persisted.Current != 2 || len(persisted.Keys) != 2 || persisted.Derive != 1 {
+		t.Errorf("RemoteService.Rotate() = %d, persisted keyring with current %d, %d keys and derive version %d", version, persisted.Current, len(persisted.Keys), persisted.Derive)
+	}
+
+	//Stored blind index tokens still match after the rotation
+	rotatedTokens, err := client.SearchTokens(person{}, "name", "card")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotatedTokens) != 1 || rotatedTokens[0] != tokens[0] {
+		t.Errorf("RemoteService.SearchTokens() after rotation = %v, want %v", rotatedTokens, tokens)
+	}
+
+	//Old ciphertexts still decrypt and can be moved to the new version
+	rewrapped, err := client.Rewrap(old)
+	if err != nil {
+		t.Fatalf("RemoteService.Rewrap() error = %v", err)
+	}
+	if !bytes.HasPrefix(rewrapped, []byte("globe:v2:")) {
+		t.Errorf("RemoteService.Rewrap() = %s, want version 2", rewrapped)
+	}
+
+	for _, ct := range [][]byte{old, rewrapped} {
+		got, err := client.DecryptByt(ct)
+		if err != nil || string(got) != "card" {
+			t.Errorf("RemoteService.DecryptByt() = %s, %v", got, err)
+		}
+	}
+
+	//A failing persist keeps the current version
+	key.OnRotate = func(Keyring) error { return errors.New("disk full") }
+	if _, err := client.Rotate(); err == nil {
+		t.Errorf("expected rotation to fail when the keyring cannot be persisted")
+	}
+	ct, err := client.EncryptStrText("card")
+	if err != nil || !bytes.HasPrefix([]byte(ct), []byte("globe:v2:")) {
+		t.Errorf("RemoteService.EncryptStrText() = %s, %v", ct, err)
+	}
+}
+
+func Test_server_datakey(t *testing.T) {
+	srv, key := newTestServer(t)
+
+	plain, encrypted, err := NewRemoteService(srv.URL, "payments", "payments-token", nil).GenerateDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plain) != 32 {
+		t.Errorf("RemoteService.GenerateDataKey() returned %d byte key", len(plain))
+	}
+
+	//The encrypted data key can be decrypted locally with the keyring as well
+	local, err := NewKeyringEncryptionService(key.Keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := local.DecryptByt(encrypted)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("encrypted data key does not decrypt to the plain data key, error %v", err)
+	}
+
+	//Binary ciphertexts are sent as standard base64
+	binary, err := local.EncryptByt([]byte("binary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(serverRequest{Ciphertext: base64.StdEncoding.EncodeToString(binary)})
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/keys/payments/decrypt", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer payments-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var decoded serverResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil || string(decoded.Plaintext) != "binary" {
+		t.Errorf("decrypt of binary ciphertext = %s, %v", decoded.Plaintext, err)
+	}
+}
@@ -1,5 +1,12 @@
 package encryption
 
+import (
+	"crypto/ed25519"
+	"io"
+	"io/fs"
+	"time"
+)
+
 //go:generate mockgen -source=service.go -destination=mock/mock_service.go -package=mock_encryption
 type EncryptionService interface {
 	EncryptToInterface(eData interface{}) (map[string]interface{}, error)
@@ -12,3 +19,100 @@ type EncryptionService interface {
 	EncryptByt(b []byte) ([]byte, error)
 	DecryptByt(b []byte) ([]byte, error)
 }
+
+//The capabilities below are implemented by every service of this package, use a type assertion on an EncryptionService to get them
+
+//Validate model types for the struct functions up front
+type ModelRegistry interface {
+	Register(models ...interface{}) error
+	MustRegister(models ...interface{})
+}
+
+//Versioned text ciphertexts
+type TextEncrypter interface {
+	EncryptStrText(str string) (string, error)
+	DecryptStrText(text string) (string, error)
+}
+
+//Chunked streaming encryption
+type StreamEncrypter interface {
+	NewEncryptWriter(w io.Writer) (io.WriteCloser, error)
+	NewDecryptReader(r io.Reader) (io.Reader, error)
+}
+
+//Atomic file encryption, encrypted file names and the decrypting fs.FS
+type FileEncrypter interface {
+	EncryptFile(src string, dst string, opts ...FileOption) error
+	DecryptFile(src string, dst string, opts ...FileOption) error
+	EncryptFileName(name string) (string, error)
+	DecryptFileName(name string) (string, error)
+	NewDecryptFS(fsys fs.FS, encryptedNames bool) fs.FS
+}
+
+//Multi-recipient envelopes
+type EnvelopeService interface {
+	DecryptEnvelope(ciphertext []byte) ([]byte, error)
+	AddRecipient(ciphertext []byte, r Recipient) ([]byte, error)
+}
+
+//Ed25519 signatures, only a service returned by WithSigningKey can sign
+type Signer interface {
+	WithSigningKey(signingKey ed25519.PrivateKey) (Signer, error)
+	SignAndEncrypt(plaintext []byte) ([]byte, error)
+	DecryptAndVerify(ciphertext []byte, trusted ...ed25519.PublicKey) ([]byte, ed25519.PublicKey, error)
+	Sign(document []byte) ([]byte, error)
+	Verify(document []byte, signature []byte, trusted ...ed25519.PublicKey) (ed25519.PublicKey, error)
+	SignPlainFields(eData interface{}) ([]byte, error)
+	VerifyPlainFields(eData interface{}, signature []byte, trusted ...ed25519.PublicKey) (ed25519.PublicKey, error)
+}
+
+//MAC over all fields of the documents of the struct functions
+type DocumentAuthenticator interface {
+	WithDocumentMAC() (EncryptionService, error)
+}
+
+//Selected values of JSON and map documents
+type DocumentEncrypter interface {
+	EncryptJSONDocument(doc []byte, selectors []string) ([]byte, error)
+	DecryptJSONDocument(doc []byte) ([]byte, error)
+	EncryptMap(doc map[string]interface{}, policy FieldPolicy) (map[string]interface{}, error)
+	DecryptMap(doc map[string]interface{}, policy FieldPolicy) (map[string]interface{}, error)
+}
+
+//Tokens that expire
+type ExpiringEncrypter interface {
+	WithClock(clock func() time.Time) ExpiringEncrypter
+	EncryptStrWithTTL(str string, ttl time.Duration) (string, error)
+	DecryptStrWithTTL(token string) (string, error)
+	EncryptBytWithTTL(b []byte, ttl time.Duration) (string, error)
+	DecryptBytWithTTL(token string) ([]byte, error)
+}
+
+//JWE compact serialization and JWK export
+type JWEEncrypter interface {
+	EncryptJWE(b []byte, alg string) (string, error)
+	DecryptJWE(token string) ([]byte, error)
+	ExportJWK() ([]byte, error)
+}
+
+//Blind index tokens to query the search and range fields
+type BlindIndexer interface {
+	SearchTokens(model interface{}, fieldName string, term string) ([]string, error)
+	RangeTokens(model interface{}, fieldName string, from interface{}, to interface{}) ([]string, error)
+}
+
+//Every service of this package implements all of the interfaces above
+var (
+	_ EncryptionService     = (*encryptionService)(nil)
+	_ ModelRegistry         = (*encryptionService)(nil)
+	_ TextEncrypter         = (*encryptionService)(nil)
+	_ StreamEncrypter       = (*encryptionService)(nil)
+	_ FileEncrypter         = (*encryptionService)(nil)
+	_ EnvelopeService       = (*encryptionService)(nil)
+	_ Signer                = (*encryptionService)(nil)
+	_ DocumentAuthenticator = (*encryptionService)(nil)
+	_ DocumentEncrypter     = (*encryptionService)(nil)
+	_ ExpiringEncrypter     = (*encryptionService)(nil)
+	_ JWEEncrypter          = (*encryptionService)(nil)
+	_ BlindIndexer          = (*encryptionService)(nil)
+)
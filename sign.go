@@ -0,0 +1,173 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+//Define signature constants
+const (
+	SignatureSize         = ed25519.PublicKeySize + ed25519.SignatureSize
+	signedMessageLabel    = "globe-protocol/encryption signed message"
+	detachedDocumentLabel = "globe-protocol/encryption detached signature"
+	plainFieldsLabel      = "globe-protocol/encryption plain fields"
+)
+
+//Get a copy of the service that signs using the given Ed25519 private key
+func (e *encryptionService) WithSigningKey(signingKey ed25519.PrivateKey) (Signer, error) {
+	if len(signingKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("signing key should be %d bytes, got %d", ed25519.PrivateKeySize, len(signingKey))
+	}
+
+	service := *e
+	service.signingKey = signingKey
+
+	return &service, nil
+}
+
+//Create signature prefixed with the public key of the signer so that the verifier knows which key signed it
+func (e *encryptionService) sign(label string, message []byte) ([]byte, error) {
+	if e.signingKey == nil {
+		return nil, errors.New("service was created without a signing key, use WithSigningKey")
+	}
+
+	signed := append([]byte(label), message...)
+	signature := ed25519.Sign(e.signingKey, signed)
+
+	return append(append([]byte{}, e.signingKey.Public().(ed25519.PublicKey)...), signature...), nil
+}
+
+//Verify signature created by sign and return the public key that created it, it has to be one of the trusted keys
+func verify(label string, message []byte, signature []byte, trusted []ed25519.PublicKey) (ed25519.PublicKey, error) {
+	if len(signature) != SignatureSize {
+		return nil, fmt.Errorf("signature should be %d bytes, got %d", SignatureSize, len(signature))
+	}
+
+	signer := ed25519.PublicKey(signature[:ed25519.PublicKeySize])
+
+	isTrusted := false
+	for _, key := range trusted {
+		if bytes.Equal(key, signer) {
+			isTrusted = true
+		}
+	}
+	if !isTrusted {
+		return nil, errors.New("message was not signed by a trusted key")
+	}
+
+	if !ed25519.Verify(signer, append([]byte(label), message...), signature[ed25519.PublicKeySize:]) {
+		return nil, errors.New("signature is invalid")
+	}
+
+	return signer, nil
+}
+
+//Sign plaintext and encrypt it together with the signature so that the identity of the sender is hidden and authenticated
+func (e *encryptionService) SignAndEncrypt(plaintext []byte) ([]byte, error) {
+	signature, err := e.sign(signedMessageLabel, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.EncryptByt(append(signature, plaintext...))
+}
+
+//Decrypt output of SignAndEncrypt and verify that it was signed by one of the trusted keys, the key of the signer is returned
+func (e *encryptionService) DecryptAndVerify(ciphertext []byte, trusted ...ed25519.PublicKey) ([]byte, ed25519.PublicKey, error) {
+	decrypted, err := e.DecryptByt(ciphertext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(decrypted) < SignatureSize {
+		return nil, nil, errors.New("decrypted message does not contain a signature")
+	}
+
+	plaintext := decrypted[SignatureSize:]
+	signer, err := verify(signedMessageLabel, plaintext, decrypted[:SignatureSize], trusted)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return plaintext, signer, nil
+}
+
+//Create detached signature of a plaintext document
+func (e *encryptionService) Sign(document []byte) ([]byte, error) {
+	return e.sign(detachedDocumentLabel, document)
+}
+
+//Verify detached signature of a plaintext document, the key of the signer is returned
+func (e *encryptionService) Verify(document []byte, signature []byte, trusted ...ed25519.PublicKey) (ed25519.PublicKey, error) {
+	return verify(detachedDocumentLabel, document, signature, trusted)
+}
+
+//Create detached signature of all fields of a struct with the `encrypted:"false"` tag
+func (e *encryptionService) SignPlainFields(eData interface{}) ([]byte, error) {
+	document, err := e.plainFieldsDocument(eData)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.sign(plainFieldsLabel, document)
+}
+
+//Verify signature created by SignPlainFields, works on the original struct as well as its encrypted version
+func (e *encryptionService) VerifyPlainFields(eData interface{}, signature []byte, trusted ...ed25519.PublicKey) (ed25519.PublicKey, error) {
+	document, err := e.plainFieldsDocument(eData)
+	if err != nil {
+		return nil, err
+	}
+
+	return verify(plainFieldsLabel, document, signature, trusted)
+}
+
+//Serialize the fields with the `encrypted:"false"` tag sorted by name, every name and value is length prefixed
+func (e *encryptionService) plainFieldsDocument(eData interface{}) ([]byte, error) {
+	object := reflect.Indirect(reflect.ValueOf(eData))
+	if object.Kind() != reflect.Struct {
+		return nil, errors.New("plain fields can only be signed for structs")
+	}
+
+	//The plan rejects invalid encrypted tags so that the signed fields are the ones the struct functions leave in plain text
+	plan := planFor(object.Type())
+	if plan.err != nil {
+		return nil, plan.err
+	}
+
+	fields := map[string]string{}
+	names := []string{}
+	for i := range plan.fields {
+		f := &plan.fields[i]
+		if f.encrypted {
+			continue
+		}
+
+		fieldName, err := f.name(decryptTagNames)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := fields[fieldName]; ok {
+			return nil, fmt.Errorf("field name %s is used by more than one plain field", fieldName)
+		}
+
+		fields[fieldName] = f.encode(object.Field(i))
+		names = append(names, fieldName)
+	}
+	sort.Strings(names)
+
+	document := []byte{}
+	for _, name := range names {
+		document = binary.BigEndian.AppendUint32(document, uint32(len(name)))
+		document = append(document, name...)
+		document = binary.BigEndian.AppendUint32(document, uint32(len(fields[name])))
+		document = append(document, fields[name]...)
+	}
+
+	return document, nil
+}
@@ -0,0 +1,231 @@
+package encryption
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+)
+
+func Test_encryptionService_SignAndEncrypt(t *testing.T) {
+	key := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+
+	senderPub, senderKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		signingKey ed25519.PrivateKey
+		trusted    []ed25519.PublicKey
+		wantErr    bool
+	}{
+		{
+			name:       "signed by trusted sender",
+			signingKey: senderKey,
+			trusted:    []ed25519.PublicKey{otherPub, senderPub},
+			wantErr:    false,
+		},
+		{
+			name:       "signed by untrusted sender",
+			signingKey: otherKey,
+			trusted:    []ed25519.PublicKey{senderPub},
+			wantErr:    true,
+		},
+		{
+			name:       "no trusted senders",
+			signingKey: senderKey,
+			trusted:    nil,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, err := NewEncryptionService(key).(Signer).WithSigningKey(tt.signingKey)
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while adding signing key", err)
+			}
+			receiver := NewEncryptionService(key)
+
+			encrypted, err := sender.SignAndEncrypt([]byte("payload"))
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while signing", err)
+			}
+
+			got, signer, err := receiver.(Signer).DecryptAndVerify(encrypted, tt.trusted...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.DecryptAndVerify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if string(got) != "payload" {
+				t.Errorf("encryptionService.DecryptAndVerify() = %s, want payload", got)
+			}
+			if !signer.Equal(tt.signingKey.Public()) {
+				t.Errorf("encryptionService.DecryptAndVerify() returned wrong signer")
+			}
+		})
+	}
+}
+
+func Test_encryptionService_SignPlainFields(t *testing.T) {
+	key := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := NewEncryptionService(key).(Signer).WithSigningKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := stringfloatbool{String: "123Test", Float64: 64.64}
+	signature, err := e.SignPlainFields(data)
+	if err != nil {
+		t.Fatalf("Did not expect error: %s while signing", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    interface{}
+		wantErr bool
+	}{
+		{
+			name:    "original struct",
+			data:    data,
+			wantErr: false,
+		},
+		{
+			name:    "encrypted struct",
+			data:    stringfloatboolEnc{String: "123Test", Float64: []byte{1, 2, 3}},
+			wantErr: false,
+		},
+		{
+			name:    "modified plain field",
+			data:    stringfloatbool{String: "456Test", Float64: 64.64},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEncryptionService(key).(Signer).VerifyPlainFields(tt.data, signature, pub)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.VerifyPlainFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_encryptionService_SignPlainFields_Tags(t *testing.T) {
+	key := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := NewEncryptionService(key).(Signer).WithSigningKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type options struct {
+		Id    string `bson:"_id" encrypted:"false"`
+		Email string `bson:"email" encrypted:"true,mode=deterministic"`
+	}
+	type invalid struct {
+		Id    string `bson:"_id" encrypted:"false"`
+		Email string `bson:"email" encrypted:"-"`
+	}
+	type plainOnly struct {
+		Id string `bson:"_id" encrypted:"false"`
+	}
+	type duplicate struct {
+		Id  string `bson:"_id" encrypted:"false"`
+		Id2 string `bson:"_id" encrypted:"false"`
+	}
+
+	signature, err := e.SignPlainFields(options{Id: "1", Email: "a@b.c"})
+	if err != nil {
+		t.Fatalf("Did not expect error: %s while signing", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    interface{}
+		wantErr bool
+	}{
+		{
+			name:    "field with options is not signed",
+			data:    options{Id: "1", Email: "d@e.f"},
+			wantErr: false,
+		},
+		{
+			name:    "same plain fields in other struct",
+			data:    plainOnly{Id: "1"},
+			wantErr: false,
+		},
+		{
+			name:    "modified plain field",
+			data:    options{Id: "2", Email: "a@b.c"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid encrypted tag",
+			data:    invalid{Id: "1"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate field name",
+			data:    duplicate{Id: "1", Id2: "1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.VerifyPlainFields(tt.data, signature, pub)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.VerifyPlainFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_encryptionService_Sign(t *testing.T) {
+	key := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewEncryptionService(key).(Signer).Sign([]byte("document")); err == nil {
+		t.Errorf("expected error when signing without signing key")
+	}
+
+	e, err := NewEncryptionService(key).(Signer).WithSigningKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := e.Sign([]byte("document"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Verify([]byte("document"), signature, pub); err != nil {
+		t.Errorf("encryptionService.Verify() error = %v", err)
+	}
+	if _, err := e.Verify([]byte("modified"), signature, pub); err == nil {
+		t.Errorf("expected error for modified document")
+	}
+}
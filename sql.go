@@ -0,0 +1,116 @@
+package encryption
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+//Service used by the Encrypted column types
+var defaultService struct {
+	mu      sync.RWMutex
+	service EncryptionService
+}
+
+//Set the service with which Encrypted values are encrypted and decrypted, it should be called before the database is used
+func SetDefaultService(service EncryptionService) {
+	defaultService.mu.Lock()
+	defer defaultService.mu.Unlock()
+
+	defaultService.service = service
+}
+
+//Get the service set with SetDefaultService
+func DefaultService() (EncryptionService, error) {
+	defaultService.mu.RLock()
+	defer defaultService.mu.RUnlock()
+
+	if defaultService.service == nil {
+		return nil, errors.New("no default encryption service is set, call SetDefaultService first")
+	}
+
+	return defaultService.service, nil
+}
+
+//Column value that is encrypted when written to and decrypted when read from the database, NULL when Valid is false.
+//T can be any basic type, time.Time, []byte or []string.
+type Encrypted[T any] struct {
+	V     T
+	Valid bool
+}
+
+//Encrypted string column
+type EncryptedString = Encrypted[string]
+
+//Encrypted binary column
+type EncryptedBytes = Encrypted[[]byte]
+
+//Create valid Encrypted value
+func NewEncrypted[T any](v T) Encrypted[T] {
+	return Encrypted[T]{V: v, Valid: true}
+}
+
+//Implement driver.Valuer, the value is stored as binary ciphertext
+func (e Encrypted[T]) Value() (driver.Value, error) {
+	if !e.Valid {
+		return nil, nil
+	}
+
+	service, err := DefaultService()
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := e.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return service.EncryptByt([]byte(plain))
+}
+
+//Implement sql.Scanner, binary and text ciphertexts are accepted
+func (e *Encrypted[T]) Scan(src interface{}) error {
+	*e = Encrypted[T]{}
+
+	var ciphertext []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		ciphertext = v
+	case string:
+		ciphertext = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into an encrypted column, it should be []byte or string", src)
+	}
+
+	service, err := DefaultService()
+	if err != nil {
+		return err
+	}
+
+	plain, err := service.DecryptByt(ciphertext)
+	if err != nil {
+		return err
+	}
+
+	return e.decode(plain)
+}
+
+func (e Encrypted[T]) encode() (string, error) {
+	return encodeValue(reflect.ValueOf(&e.V).Elem())
+}
+
+func (e *Encrypted[T]) decode(plain []byte) error {
+	val, err := decodeValue(string(plain), reflect.TypeOf(&e.V).Elem())
+	if err != nil {
+		return err
+	}
+
+	e.V, e.Valid = val.(T), true
+
+	return nil
+}
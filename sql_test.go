@@ -0,0 +1,134 @@
+package encryption
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func setTestDefaultService(t *testing.T, service EncryptionService) {
+	SetDefaultService(service)
+	t.Cleanup(func() { SetDefaultService(nil) })
+}
+
+//Store value through the driver.Valuer and read it back through sql.Scanner like database/sql does
+func roundTripColumn[T any](t *testing.T, in Encrypted[T], asString bool) (Encrypted[T], driver.Value) {
+	stored, err := driver.DefaultParameterConverter.ConvertValue(in)
+	if err != nil {
+		t.Fatalf("Encrypted.Value() error = %v", err)
+	}
+
+	var src interface{} = stored
+	if b, ok := stored.([]byte); ok && asString {
+		src = string(b)
+	}
+
+	var out Encrypted[T]
+	if err := out.Scan(src); err != nil {
+		t.Fatalf("Encrypted.Scan() error = %v", err)
+	}
+
+	return out, stored
+}
+
+func Test_Encrypted_Value(t *testing.T) {
+	setTestDefaultService(t, NewEncryptionService([]byte("0123456789abcdef0123456789abcdef")))
+
+	t.Run("string", func(t *testing.T) {
+		got, stored := roundTripColumn(t, NewEncrypted("078-05-1120"), false)
+		if bytes.Contains(stored.([]byte), []byte("078-05-1120")) {
+			t.Errorf("Encrypted.Value() stored plaintext")
+		}
+		if got != NewEncrypted("078-05-1120") {
+			t.Errorf("Encrypted.Scan() = %v, want %v", got, NewEncrypted("078-05-1120"))
+		}
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		in := EncryptedBytes{V: []byte{0, 1, 2, 255}, Valid: true}
+		got, _ := roundTripColumn(t, in, false)
+		if !reflect.DeepEqual(got, in) {
+			t.Errorf("Encrypted.Scan() = %v, want %v", got, in)
+		}
+	})
+
+	t.Run("int32 from string column", func(t *testing.T) {
+		got, _ := roundTripColumn(t, NewEncrypted(int32(100000)), true)
+		if got != NewEncrypted(int32(100000)) {
+			t.Errorf("Encrypted.Scan() = %v, want 100000", got)
+		}
+	})
+
+	t.Run("time", func(t *testing.T) {
+		in := NewEncrypted(time.Date(2024, 3, 1, 12, 30, 0, 5, time.UTC))
+		got, _ := roundTripColumn(t, in, false)
+		if !got.V.Equal(in.V) || !got.Valid {
+			t.Errorf("Encrypted.Scan() = %v, want %v", got, in)
+		}
+	})
+
+	t.Run("null", func(t *testing.T) {
+		got, stored := roundTripColumn(t, EncryptedString{}, false)
+		if stored != nil {
+			t.Errorf("Encrypted.Value() = %v, want nil", stored)
+		}
+		if got.Valid {
+			t.Errorf("Encrypted.Scan() of NULL = %v, want invalid", got)
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		if _, err := NewEncrypted(map[string]int{}).Value(); err == nil {
+			t.Errorf("Encrypted.Value() of map should fail")
+		}
+	})
+}
+
+func Test_Encrypted_Scan(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	setTestDefaultService(t, NewEncryptionService(key))
+
+	otherKey, _ := NewEncryptionService([]byte("fedcba9876543210fedcba9876543210")).EncryptStr("42")
+	text, _ := NewEncryptionService(key).(TextEncrypter).EncryptStrText("42")
+	notANumber, _ := NewEncryptionService(key).EncryptStr("forty-two")
+
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    Encrypted[int]
+		wantErr bool
+	}{
+		{name: "null", src: nil, want: Encrypted[int]{}},
+		{name: "text ciphertext", src: text, want: NewEncrypted(42)},
+		{name: "other key", src: otherKey, wantErr: true},
+		{name: "invalid plaintext", src: notANumber, wantErr: true},
+		{name: "unsupported source", src: int64(42), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewEncrypted(7)
+			err := got.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Encrypted.Scan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Encrypted.Scan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Encrypted_NoDefaultService(t *testing.T) {
+	setTestDefaultService(t, nil)
+
+	if _, err := NewEncrypted("a").Value(); err == nil {
+		t.Errorf("Encrypted.Value() without default service should fail")
+	}
+
+	//NULL needs no service
+	if v, err := (EncryptedString{}).Value(); err != nil || v != nil {
+		t.Errorf("Encrypted.Value() of NULL = %v, %v, want nil, nil", v, err)
+	}
+}
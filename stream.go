@@ -0,0 +1,212 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//Define stream format constants
+const (
+	StreamMagic      = "GLBS"
+	StreamVersion    = 1
+	StreamChunkSize  = 64 * 1024
+	streamSaltSize   = 16
+	streamHeaderSize = len(StreamMagic) + 1 + streamSaltSize
+	streamKeyLabel   = "globe-protocol/encryption stream key"
+)
+
+//Create the AEAD of a single stream, every stream uses its own key derived from the random salt in the header
+func (e *encryptionService) streamGCM(header []byte) (cipher.AEAD, error) {
+	salt := header[len(StreamMagic)+1:]
+
+	key, err := e.deriveKey(streamKeyLabel + string(salt))
+	if err != nil {
+		return nil, err
+	}
+
+	return newGCM(key)
+}
+
+//Nonce consists of the chunk counter followed by a flag marking the final chunk
+func streamNonce(aesGCM cipher.AEAD, counter uint64, last bool) []byte {
+	nonce := make([]byte, aesGCM.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-9:len(nonce)-1], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+
+	return nonce
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	aesGCM  cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+//Get a writer that encrypts everything written to it in authenticated chunks, Close has to be called to write the final chunk
+func (e *encryptionService) NewEncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	header := make([]byte, streamHeaderSize)
+	copy(header, StreamMagic)
+	header[len(StreamMagic)] = StreamVersion
+	if _, err := io.ReadFull(rand.Reader, header[len(StreamMagic)+1:]); err != nil {
+		return nil, err
+	}
+
+	aesGCM, err := e.streamGCM(header)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write stream header, the following error occured: %s", err)
+	}
+
+	return &encryptWriter{
+		w:      w,
+		aesGCM: aesGCM,
+		header: header,
+		buf:    make([]byte, 0, StreamChunkSize),
+	}, nil
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	if ew.closed {
+		return 0, errors.New("cannot write to closed encrypt writer")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		n := copy(ew.buf[len(ew.buf):cap(ew.buf)], p)
+		ew.buf = ew.buf[:len(ew.buf)+n]
+		p = p[n:]
+		written += n
+
+		//Only flush full chunks, the final chunk is written by Close
+		if len(ew.buf) == cap(ew.buf) {
+			if err := ew.flush(false); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+func (ew *encryptWriter) flush(last bool) error {
+	if ew.counter == ^uint64(0) {
+		return errors.New("stream exceeded the maximum number of chunks")
+	}
+
+	sealed := ew.aesGCM.Seal(nil, streamNonce(ew.aesGCM, ew.counter, last), ew.buf, ew.header)
+	if _, err := ew.w.Write(sealed); err != nil {
+		return err
+	}
+
+	ew.counter++
+	ew.buf = ew.buf[:0]
+
+	return nil
+}
+
+//Write the final chunk, the underlying writer is not closed
+func (ew *encryptWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+
+	return ew.flush(true)
+}
+
+type decryptReader struct {
+	r       io.Reader
+	aesGCM  cipher.AEAD
+	header  []byte
+	chunk   []byte
+	buf     []byte
+	counter uint64
+	done    bool
+	err     error
+}
+
+//Get a reader that decrypts a stream created by NewEncryptWriter, truncated, reordered or modified streams result in an error
+func (e *encryptionService) NewDecryptReader(r io.Reader) (io.Reader, error) {
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read stream header, the following error occured: %s", err)
+	}
+
+	if !bytes.Equal(header[:len(StreamMagic)], []byte(StreamMagic)) {
+		return nil, errors.New("input is not an encrypted stream")
+	}
+	if header[len(StreamMagic)] != StreamVersion {
+		return nil, fmt.Errorf("stream version %d is not supported", header[len(StreamMagic)])
+	}
+
+	aesGCM, err := e.streamGCM(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:      r,
+		aesGCM: aesGCM,
+		header: header,
+		chunk:  make([]byte, StreamChunkSize+aesGCM.Overhead()),
+	}, nil
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.buf) == 0 {
+		if dr.err != nil {
+			return 0, dr.err
+		}
+		if dr.done {
+			return 0, io.EOF
+		}
+
+		dr.err = dr.next()
+	}
+
+	n := copy(p, dr.buf)
+	dr.buf = dr.buf[n:]
+
+	return n, nil
+}
+
+//Read and decrypt the next chunk, a chunk shorter than the full size is the final chunk
+func (dr *decryptReader) next() error {
+	n, err := io.ReadFull(dr.r, dr.chunk)
+	last := false
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+
+	if last && n < dr.aesGCM.Overhead() {
+		return errors.New("encrypted stream is truncated")
+	}
+
+	plain, err := dr.aesGCM.Open(dr.chunk[:0], streamNonce(dr.aesGCM, dr.counter, last), dr.chunk[:n], dr.header)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt chunk %d of stream, it is truncated, reordered or modified", dr.counter)
+	}
+
+	dr.counter++
+	dr.buf = plain
+	dr.done = last
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func Test_encryptionService_Stream(t *testing.T) {
+	encChunk := StreamChunkSize + 16
+
+	tests := []struct {
+		name    string
+		size    int
+		tamper  func(b []byte) []byte
+		wantErr bool
+	}{
+		{
+			name:    "empty stream",
+			size:    0,
+			wantErr: false,
+		},
+		{
+			name:    "single partial chunk",
+			size:    100,
+			wantErr: false,
+		},
+		{
+			name:    "exactly one chunk",
+			size:    StreamChunkSize,
+			wantErr: false,
+		},
+		{
+			name:    "multiple chunks",
+			size:    StreamChunkSize*2 + 5,
+			wantErr: false,
+		},
+		{
+			name: "truncated at chunk boundary",
+			size: StreamChunkSize*2 + 5,
+			tamper: func(b []byte) []byte {
+				return b[:streamHeaderSize+encChunk]
+			},
+			wantErr: true,
+		},
+		{
+			name: "final chunk removed after full chunk",
+			size: StreamChunkSize,
+			tamper: func(b []byte) []byte {
+				return b[:streamHeaderSize+encChunk]
+			},
+			wantErr: true,
+		},
+		{
+			name: "reordered chunks",
+			size: StreamChunkSize * 3,
+			tamper: func(b []byte) []byte {
+				out := append([]byte{}, b[:streamHeaderSize]...)
+				out = append(out, b[streamHeaderSize+encChunk:streamHeaderSize+2*encChunk]...)
+				out = append(out, b[streamHeaderSize:streamHeaderSize+encChunk]...)
+				return append(out, b[streamHeaderSize+2*encChunk:]...)
+			},
+			wantErr: true,
+		},
+		{
+			name: "modified ciphertext",
+			size: 1000,
+			tamper: func(b []byte) []byte {
+				b[streamHeaderSize+10] ^= 1
+				return b
+			},
+			wantErr: true,
+		},
+		{
+			name: "modified header",
+			size: 1000,
+			tamper: func(b []byte) []byte {
+				b[len(StreamMagic)+2] ^= 1
+				return b
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := encryptionService{
+				key: []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254},
+			}
+
+			plain := make([]byte, tt.size)
+			if _, err := rand.Read(plain); err != nil {
+				t.Fatal(err)
+			}
+
+			var encrypted bytes.Buffer
+			w, err := e.NewEncryptWriter(&encrypted)
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while creating writer", err)
+			}
+			if _, err := w.Write(plain); err != nil {
+				t.Fatalf("Did not expect error: %s while writing", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Did not expect error: %s while closing writer", err)
+			}
+
+			b := encrypted.Bytes()
+			if tt.tamper != nil {
+				b = tt.tamper(b)
+			}
+
+			r, err := e.NewDecryptReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while creating reader", err)
+			}
+
+			got, err := io.ReadAll(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("decryptReader.Read() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && !bytes.Equal(got, plain) {
+				t.Errorf("decrypted stream does not match input")
+			}
+		})
+	}
+}
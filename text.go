@@ -0,0 +1,123 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//Define text ciphertext constants, text ciphertexts look like globe:v1:<base64>
+const (
+	TextPrefix = "globe:v"
+)
+
+//Encrypt string to a text ciphertext that is safe to use in logs, environment variables and YAML
+func (e *encryptionService) EncryptStrText(str string) (string, error) {
+	b, err := e.EncryptStr(str)
+	if err != nil {
+		return "", err
+	}
+
+	//The encryption server already returns text ciphertexts
+	if e.remote != nil {
+		return string(b), nil
+	}
+
+	return formatTextCiphertext(e.keyVersion(), b), nil
+}
+
+//Decrypt text ciphertext created by EncryptStrText using the key version it names
+func (e *encryptionService) DecryptStrText(text string) (string, error) {
+	plain, err := e.openText([]byte(text))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt string, the following error occured: %s", err)
+	}
+
+	return string(plain), nil
+}
+
+func formatTextCiphertext(version uint32, b []byte) string {
+	return TextPrefix + strconv.FormatUint(uint64(version), 10) + ":" + base64.RawURLEncoding.EncodeToString(b)
+}
+
+//Split text ciphertext into key version and binary ciphertext
+func parseTextCiphertext(text []byte) (uint32, []byte, error) {
+	if !bytes.HasPrefix(text, []byte(TextPrefix)) {
+		return 0, nil, errors.New("input is not a text ciphertext")
+	}
+
+	version, payload, found := strings.Cut(string(text[len(TextPrefix):]), ":")
+	if !found {
+		return 0, nil, errors.New("text ciphertext has no key version")
+	}
+
+	v, err := strconv.ParseUint(version, 10, 32)
+	if err != nil {
+		return 0, nil, fmt.Errorf("text ciphertext has invalid key version %q", version)
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(payload))
+	if err != nil {
+		return 0, nil, fmt.Errorf("text ciphertext is not valid URL-safe base64, the following error occured: %s", err)
+	}
+
+	return uint32(v), b, nil
+}
+
+//Decrypt text ciphertext, version 0 is used for envelopes of services without a symmetric key
+func (e encryptionService) openText(text []byte) ([]byte, error) {
+	if e.remote != nil {
+		return e.remote.open(text)
+	}
+
+	version, b, err := parseTextCiphertext(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == 0 {
+		if len(e.recipients) == 0 {
+			return nil, errors.New("text ciphertext contains an envelope but the service has no private key")
+		}
+
+		return e.openEnvelope(b)
+	}
+
+	key, err := e.keyForVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcmSealer{aesGCM: aesGCM}.open(b)
+}
+
+//Get ciphertext stored in a []byte field or in a string field holding a text ciphertext
+func fieldCiphertext(v reflect.Value) []byte {
+	if v.Kind() == reflect.String {
+		if v.Len() == 0 {
+			return nil
+		}
+
+		return []byte(v.String())
+	}
+
+	return v.Bytes()
+}
+
+//Get binary ciphertext of a text ciphertext so that both forms authenticate the same way
+func textPayload(b []byte) []byte {
+	if _, payload, err := parseTextCiphertext(b); err == nil {
+		return payload
+	}
+
+	return b
+}
@@ -0,0 +1,141 @@
+package encryption
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type stringfloatboolPlain struct {
+	String  string  `bson:"String" encrypted:"false"`
+	Float64 float64 `bson:"Float64"`
+	Bool    bool    `bson:"Bool"`
+}
+
+type stringfloatboolText struct {
+	String  string `bson:"String" encrypted:"false"`
+	Float64 string `bson:"Float64"`
+	Bool    string `bson:"Bool"`
+}
+
+func Test_encryptionService_EncryptStrText(t *testing.T) {
+	oldKey := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+	newKey := []byte("0123456789abcdef0123456789abcdef")
+
+	rotated, err := NewKeyringEncryptionService(Keyring{Current: 3, Keys: map[uint32][]byte{1: oldKey, 3: newKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		encrypter  EncryptionService
+		decrypter  EncryptionService
+		wantPrefix string
+		tamper     func(text string) string
+		wantErr    bool
+	}{
+		{
+			name:       "default key version",
+			encrypter:  NewEncryptionService(oldKey),
+			decrypter:  NewEncryptionService(oldKey),
+			wantPrefix: "globe:v1:",
+		},
+		{
+			name:       "old version decrypted by keyring",
+			encrypter:  NewEncryptionService(oldKey),
+			decrypter:  rotated,
+			wantPrefix: "globe:v1:",
+		},
+		{
+			name:       "current keyring version",
+			encrypter:  rotated,
+			decrypter:  rotated,
+			wantPrefix: "globe:v3:",
+		},
+		{
+			name:       "unknown version",
+			encrypter:  rotated,
+			decrypter:  NewEncryptionService(newKey),
+			wantPrefix: "globe:v3:",
+			wantErr:    true,
+		},
+		{
+			name:       "version changed",
+			encrypter:  rotated,
+			decrypter:  rotated,
+			wantPrefix: "globe:v3:",
+			tamper: func(text string) string {
+				return strings.Replace(text, "globe:v3:", "globe:v1:", 1)
+			},
+			wantErr: true,
+		},
+		{
+			name:       "mangled base64",
+			encrypter:  rotated,
+			decrypter:  rotated,
+			wantPrefix: "globe:v3:",
+			tamper: func(text string) string {
+				return text + "=="
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := tt.encrypter.(TextEncrypter).EncryptStrText("secret value")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(text, tt.wantPrefix) {
+				t.Errorf("encryptionService.EncryptStrText() = %v, want prefix %v", text, tt.wantPrefix)
+			}
+
+			if tt.tamper != nil {
+				text = tt.tamper(text)
+			}
+
+			got, err := tt.decrypter.(TextEncrypter).DecryptStrText(text)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.DecryptStrText() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != "secret value" {
+				t.Errorf("encryptionService.DecryptStrText() = %v, want %v", got, "secret value")
+			}
+
+			//DecryptStr accepts the text form as well
+			got, err = tt.decrypter.DecryptStr([]byte(text))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encryptionService.DecryptStr() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != "secret value" {
+				t.Errorf("encryptionService.DecryptStr() = %v, want %v", got, "secret value")
+			}
+		})
+	}
+}
+
+func Test_encryptionService_Decrypt_textFields(t *testing.T) {
+	e := NewEncryptionService([]byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254})
+
+	float64Text, err := e.(TextEncrypter).EncryptStrText("64.64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	boolText, err := e.(TextEncrypter).EncryptStrText("true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := e.Decrypt(stringfloatboolText{String: "123Test", Float64: float64Text, Bool: boolText}, stringfloatboolPlain{})
+	if err != nil {
+		t.Fatalf("encryptionService.Decrypt() error = %v", err)
+	}
+
+	want := &stringfloatboolPlain{String: "123Test", Float64: 64.64, Bool: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("encryptionService.Decrypt() = %+v, want %+v", got, want)
+	}
+}
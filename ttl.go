@@ -0,0 +1,135 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+//Define expiring token constants
+const (
+	TokenMagic     = "GLBT"
+	TokenVersion   = 1
+	TokenClockSkew = time.Minute
+	tokenHeadSize  = len(TokenMagic) + 1 + 16
+)
+
+//Errors returned when the validity period of a token does not contain the current time
+var (
+	ErrTokenExpired     = errors.New("token has expired")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+)
+
+//Get a copy of the service that uses the given clock to issue and validate expiring tokens
+func (e *encryptionService) WithClock(clock func() time.Time) ExpiringEncrypter {
+	service := *e
+	service.clock = clock
+
+	return &service
+}
+
+func (e *encryptionService) now() time.Time {
+	if e.clock == nil {
+		return time.Now()
+	}
+
+	return e.clock()
+}
+
+//Encrypt string to a URL-safe token that can only be decrypted until the ttl has passed
+func (e *encryptionService) EncryptStrWithTTL(str string, ttl time.Duration) (string, error) {
+	return e.EncryptBytWithTTL([]byte(str), ttl)
+}
+
+//Encrypt []byte to a URL-safe token that can only be decrypted until the ttl has passed
+func (e *encryptionService) EncryptBytWithTTL(b []byte, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		return "", errors.New("ttl should be positive")
+	}
+
+	issuedAt := e.now()
+	expiresAt := issuedAt.Add(ttl)
+
+	//Header is readable without decrypting, it is encrypted together with the payload so that it cannot be changed
+	header := append([]byte(TokenMagic), TokenVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(issuedAt.Unix()))
+	header = binary.BigEndian.AppendUint64(header, uint64(expiresAt.Unix()))
+
+	s, err := e.newSealer()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := s.seal(append(append([]byte{}, header...), b...))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append(header, sealed...)), nil
+}
+
+//Decrypt token created by EncryptStrWithTTL, expired tokens and tokens issued in the future are rejected
+func (e *encryptionService) DecryptStrWithTTL(token string) (string, error) {
+	b, err := e.DecryptBytWithTTL(token)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+//Decrypt token created by EncryptBytWithTTL, expired tokens and tokens issued in the future are rejected
+func (e *encryptionService) DecryptBytWithTTL(token string) ([]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("token is not valid URL-safe base64")
+	}
+
+	issuedAt, expiresAt, err := parseTokenHeader(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := e.newSealer()
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := e.getPlainBytes(raw[tokenHeadSize:], s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token, the following error occured: %s", err)
+	}
+
+	if len(decrypted) < tokenHeadSize || !bytes.Equal(decrypted[:tokenHeadSize], raw[:tokenHeadSize]) {
+		return nil, errors.New("token header does not match encrypted header")
+	}
+
+	//Only check the times after decrypting so that they are known to be authentic
+	now := e.now()
+	if now.Add(TokenClockSkew).Before(issuedAt) {
+		return nil, ErrTokenNotYetValid
+	}
+	if !now.Before(expiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	return decrypted[tokenHeadSize:], nil
+}
+
+//Read issue and expiry time from the header of a raw token
+func parseTokenHeader(raw []byte) (time.Time, time.Time, error) {
+	if len(raw) < tokenHeadSize || !bytes.Equal(raw[:len(TokenMagic)], []byte(TokenMagic)) {
+		return time.Time{}, time.Time{}, errors.New("input is not an expiring token")
+	}
+	if raw[len(TokenMagic)] != TokenVersion {
+		return time.Time{}, time.Time{}, fmt.Errorf("token version %d is not supported", raw[len(TokenMagic)])
+	}
+
+	issuedAt := time.Unix(int64(binary.BigEndian.Uint64(raw[len(TokenMagic)+1:])), 0)
+	expiresAt := time.Unix(int64(binary.BigEndian.Uint64(raw[len(TokenMagic)+9:])), 0)
+
+	return issuedAt, expiresAt, nil
+}
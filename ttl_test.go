@@ -0,0 +1,84 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func Test_encryptionService_EncryptStrWithTTL(t *testing.T) {
+	issued := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		now     time.Time
+		tamper  func(token string) string
+		wantErr error
+	}{
+		{
+			name: "valid token",
+			ttl:  time.Hour,
+			now:  issued.Add(30 * time.Minute),
+		},
+		{
+			name:    "expired token",
+			ttl:     time.Hour,
+			now:     issued.Add(time.Hour),
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:    "token issued in the future",
+			ttl:     time.Hour,
+			now:     issued.Add(-time.Hour),
+			wantErr: ErrTokenNotYetValid,
+		},
+		{
+			name: "token issued within clock skew",
+			ttl:  time.Hour,
+			now:  issued.Add(-30 * time.Second),
+		},
+		{
+			name: "extended expiry",
+			ttl:  time.Hour,
+			now:  issued.Add(2 * time.Hour),
+			tamper: func(token string) string {
+				raw, _ := base64.RawURLEncoding.DecodeString(token)
+				raw[tokenHeadSize-2]++
+				return base64.RawURLEncoding.EncodeToString(raw)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := []byte{176, 55, 108, 116, 181, 15, 21, 190, 134, 27, 183, 18, 48, 179, 221, 123, 225, 172, 55, 54, 142, 158, 173, 59, 77, 239, 116, 99, 248, 15, 228, 254}
+
+			issuer := NewEncryptionService(key).(ExpiringEncrypter).WithClock(func() time.Time { return issued })
+			verifier := NewEncryptionService(key).(ExpiringEncrypter).WithClock(func() time.Time { return tt.now })
+
+			token, err := issuer.EncryptStrWithTTL("reset-password:1234", tt.ttl)
+			if err != nil {
+				t.Fatalf("Did not expect error: %s while encrypting", err)
+			}
+
+			wantErr := tt.wantErr != nil
+			if tt.tamper != nil {
+				token = tt.tamper(token)
+				wantErr = true
+			}
+
+			got, err := verifier.DecryptStrWithTTL(token)
+			if (err != nil) != wantErr {
+				t.Errorf("encryptionService.DecryptStrWithTTL() error = %v, wantErr %v", err, wantErr)
+				return
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("encryptionService.DecryptStrWithTTL() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !wantErr && got != "reset-password:1234" {
+				t.Errorf("encryptionService.DecryptStrWithTTL() = %v, want %v", got, "reset-password:1234")
+			}
+		})
+	}
+}